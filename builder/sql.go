@@ -5,8 +5,11 @@ import (
 	"strings"
 )
 
+// error 节点 (解析时跳过的输入) 不生成 SQL
 func Node(node *parser.Node) string {
 	switch node.Type {
+	case parser.ErrorType:
+		return ""
 	case "select":
 		return Select(node)
 	case "having":
@@ -43,6 +46,9 @@ func Node(node *parser.Node) string {
 func Nodes(nodes []*parser.Node) []string {
 	ss := make([]string, 0, len(nodes))
 	for _, node := range nodes {
+		if parser.IsError(node) {
+			continue
+		}
 		ss = append(ss, Node(node))
 	}
 	return ss
@@ -0,0 +1,20 @@
+package builder
+
+import (
+	"testing"
+
+	"github.com/Aiyane/parsec-go/parser"
+)
+
+func TestSkipErrorNodes(t *testing.T) {
+	nodes := parser.ParseSQL(`(SELECT (WHERE (FROM t) (AS [x )] ) (= a 1)) a (AS b) c)`)
+	if nodes == nil {
+		t.Fatal("parse failed")
+	}
+	if n := len(parser.Errors(nodes)); n != 2 {
+		t.Fatalf("Errors = %d, want 2", n)
+	}
+	if got, want := Node(nodes[0]), "(SELECT a, c FROM t WHERE a = 1)"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Aiyane/parsec-go/gen"
+	"github.com/Aiyane/parsec-go/parser"
+)
+
+// parsec-gen -pkg sexp -o sexp_parser.go sexp.peg
+func main() {
+	pkg := flag.String("pkg", "main", "package name of the generated file")
+	out := flag.String("o", "", "output file, default stdout")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: parsec-gen [-pkg name] [-o file] grammar")
+		os.Exit(2)
+	}
+
+	src, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	g, err := parser.LoadGrammar(string(src), nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	code, err := gen.Generate(g, *pkg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(code)
+	} else if err := os.WriteFile(*out, code, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,4 @@
+// example 是 parsec-gen 根据 example.peg 生成的解析器, 用于检查生成的代码与解释执行的结果一致.
+package example
+
+//go:generate go run ../../cmd/parsec-gen -pkg example -o parser.go example.peg
@@ -0,0 +1,2152 @@
+// Code generated by parsec-gen. DO NOT EDIT.
+
+package example
+
+import "github.com/Aiyane/parsec-go/parser"
+
+type memoEntry struct {
+	done  bool
+	ok    bool
+	nodes []*parser.Node
+	pos   int
+}
+
+type Parser struct {
+	toks  []*parser.Node
+	preds map[string]func(*parser.Node) bool
+	memo  [][]memoEntry
+	rules []string // 正在解析的规则, 用于 ParseError 的规则名
+}
+
+// preds 为 nil 时使用 parser.Preds
+func New(toks []*parser.Node, preds map[string]func(*parser.Node) bool) *Parser {
+	if preds == nil {
+		preds = parser.Preds
+	}
+	return &Parser{toks: toks, preds: preds, memo: make([][]memoEntry, memoSize)}
+}
+
+// 从规则 rule 开始解析, 失败时返回 nil
+func Parse(rule string, toks []*parser.Node) ([]*parser.Node, []*parser.Node) {
+	return New(toks, nil).Parse(rule)
+}
+
+func (p *Parser) Parse(rule string) ([]*parser.Node, []*parser.Node) {
+	ns, rest, _ := p.ParseErr(rule)
+	return ns, rest
+}
+
+// 同 Parse, @^ 剪枝后的失败作为 error 返回
+func (p *Parser) ParseErr(rule string) ([]*parser.Node, []*parser.Node, error) {
+	f, ok := rules[rule]
+	if !ok {
+		return nil, nil, nil
+	}
+	ns, pos, ok, err := p.try(f, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, nil
+	}
+	if ns == nil {
+		ns = make([]*parser.Node, 0)
+	}
+	return ns, p.toks[pos:], nil
+}
+
+// 运行 f, 接住其中 @^ 抛出的 ParseError, 规则名取出错时最内层的规则
+func (p *Parser) try(f func(*Parser, int) ([]*parser.Node, int, bool), pos int) (ns []*parser.Node, r int, ok bool, err *parser.ParseError) {
+	depth := len(p.rules)
+	defer func() {
+		if x := recover(); x != nil {
+			e, isErr := x.(*parser.ParseError)
+			if !isErr {
+				panic(x)
+			}
+			if e.Rule == "" && len(p.rules) > 0 {
+				e.Rule = p.rules[len(p.rules)-1]
+			}
+			p.rules = p.rules[:depth]
+			ns, r, ok, err = nil, 0, false, e
+		}
+	}()
+	ns, r, ok = f(p, pos)
+	return ns, r, ok, nil
+}
+
+func (p *Parser) cached(id, pos int, f func(*Parser, int) ([]*parser.Node, int, bool)) ([]*parser.Node, int, bool) {
+	if p.memo[id] == nil {
+		p.memo[id] = make([]memoEntry, len(p.toks)+1)
+	}
+	if e := p.memo[id][pos]; e.done {
+		return e.nodes, e.pos, e.ok
+	}
+	ns, r, ok := f(p, pos)
+	p.memo[id][pos] = memoEntry{done: true, ok: ok, nodes: ns, pos: r}
+	return ns, r, ok
+}
+
+// 在 pos 处运行解释执行的组合子, 用于字符级的运算符
+func (p *Parser) apply(c parser.Combinator, pos int) ([]*parser.Node, int, bool) {
+	t, r := c()(p.toks[pos:], nil, nil)
+	if t == nil {
+		return nil, 0, false
+	}
+	return t, len(p.toks) - len(r), true
+}
+
+func (p *Parser) isRune(pos int) bool {
+	return pos < len(p.toks) && parser.IsRune(p.toks[pos])
+}
+
+func (p *Parser) token(pos int, s string) bool {
+	return pos < len(p.toks) && (parser.IsTokenType(p.toks[pos]) || parser.IsNumber(p.toks[pos])) && p.toks[pos].Text == s
+}
+
+func (p *Parser) startOf(pos int) int {
+	if pos < len(p.toks) {
+		return p.toks[pos].Start
+	}
+	if pos > 0 {
+		return p.toks[pos-1].End
+	}
+	return 0
+}
+
+func dropPhantoms(ns []*parser.Node) []*parser.Node {
+	ret := make([]*parser.Node, 0, len(ns))
+	for _, n := range ns {
+		if !parser.IsPhantom(n) {
+			ret = append(ret, n)
+		}
+	}
+	return ret
+}
+
+func phantom(ns []*parser.Node) []*parser.Node {
+	if len(ns) == 0 {
+		return nil
+	}
+	return []*parser.Node{{Type: parser.PhantomType, Start: ns[0].Start, End: ns[len(ns)-1].End}}
+}
+
+var rules = map[string]func(*Parser, int) ([]*parser.Node, int, bool){
+	"top":       (*Parser).rule0_top,
+	"expr":      (*Parser).rule1_expr,
+	"select":    (*Parser).rule2_select,
+	"where":     (*Parser).rule3_where,
+	"from":      (*Parser).rule4_from,
+	"field":     (*Parser).rule5_field,
+	"func":      (*Parser).rule6_func,
+	"open":      (*Parser).rule7_open,
+	"close":     (*Parser).rule8_close,
+	"nonParens": (*Parser).rule9_nonParens,
+	"list":      (*Parser).rule10_list,
+	"opt":       (*Parser).rule11_opt,
+	"num":       (*Parser).rule12_num,
+	"calc":      (*Parser).rule13_calc,
+	"calcr":     (*Parser).rule14_calcr,
+	"rel":       (*Parser).rule15_rel,
+	"stmts":     (*Parser).rule16_stmts,
+	"stmt":      (*Parser).rule17_stmt,
+	"lim":       (*Parser).rule18_lim,
+	"chars":     (*Parser).rule19_chars,
+	"word":      (*Parser).rule20_word,
+	"digits":    (*Parser).rule21_digits,
+	"assign":    (*Parser).rule22_assign,
+	"sepby":     (*Parser).rule23_sepby,
+	"sepend":    (*Parser).rule24_sepend,
+	"empty":     (*Parser).rule25_empty,
+	"till":      (*Parser).rule26_till,
+	"peek":      (*Parser).rule27_peek,
+}
+
+const memoSize = 1
+
+var (
+	c1  = parser.S["@_"]("SELECT")
+	c2  = parser.S["@_"]("WHERE")
+	c3  = parser.S["@_"]("FROM")
+	c4  = parser.S["@_"](".")
+	c5  = parser.S["$$"]("SELECT")
+	c6  = parser.S["$$"]("WHERE")
+	c7  = parser.S["$$"]("FROM")
+	c8  = parser.S["$$"](".")
+	c9  = parser.S["@~"]("(")
+	c10 = parser.S["@~"]("[")
+	c11 = parser.S["@~"](")")
+	c12 = parser.S["@~"]("]")
+	c13 = parser.S["@_"](",")
+	c14 = parser.S["$$"]("x")
+	c15 = parser.S["$$"]("y")
+	c16 = parser.S["$$"]("z")
+	c17 = parser.S["$$"]("w")
+	c18 = parser.S["$$"]("q")
+	c19 = parser.S["$$"]("k")
+	c20 = parser.S["$$"]("m")
+	c21 = parser.S["$$"]("n")
+	c22 = parser.S["$$"]("-")
+	c23 = parser.S["$$"]("+")
+	c24 = parser.S["$$"]("++")
+	c25 = parser.S["$$"]("^")
+	c26 = parser.S["$$"]("<")
+	c27 = parser.S["$$"](">")
+	c28 = parser.S["$$"](";")
+	c29 = parser.S["@_"](";")
+	c30 = parser.S["$$"]("let")
+	c31 = parser.S["$$"]("LIMIT")
+	c32 = parser.S["$$"]("0")
+	c33 = parser.S["$$"]("10")
+	c34 = parser.S["$str"]("->")
+	c35 = parser.S["$regex"]("[a-z]+(-[a-z]+)*")
+	c36 = parser.S["$class"]("0-9")
+	c37 = parser.S["$class"]("0-9")
+	c38 = parser.S["@_"]("=")
+	c39 = parser.Kind("number")
+	c40 = parser.S["$regex"]("[^;]*")
+	c41 = parser.S["$$"](";")
+	c42 = parser.S["$$"]("begin")
+	c43 = parser.S["@_"](",")
+	c44 = parser.S["$$"]("end")
+	c45 = parser.S["$$"](",")
+	c46 = parser.S["$$"]("x")
+	c47 = parser.S["$$"](",")
+	c48 = parser.S["@_"](";")
+	c49 = parser.S["$$"]("a")
+	c50 = parser.S["$$"]("a")
+	c51 = parser.S["$$"]("b")
+)
+
+func (p *Parser) e1(pos int) ([]*parser.Node, int, bool) {
+	if pos < len(p.toks) && p.toks[pos].Type == "eof" {
+		return nil, pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e2(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.rule1_expr(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e1(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) rule0_top(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "top")
+	ns, r, ok := p.e2(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e3(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.rule2_select(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.rule3_where(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.rule4_from(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.rule5_field(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.rule6_func(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.rule9_nonParens(pos); ok {
+		return t, r, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e4(pos int) ([]*parser.Node, int, bool) {
+	return p.cached(0, pos, (*Parser).e3)
+}
+
+func (p *Parser) rule1_expr(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "expr")
+	ns, r, ok := p.e4(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e5(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c1, pos)
+	}
+	if p.token(pos, "SELECT") {
+		return nil, pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e6(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.rule1_expr(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e7(pos int) ([]*parser.Node, int, bool) {
+	ns, pos, ok := p.e6(pos)
+	if !ok {
+		return nil, 0, false
+	}
+	for pos < len(p.toks) {
+		t, r, ok := p.e6(pos)
+		if !ok {
+			break
+		}
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) e8(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.rule7_open(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e5(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.rule1_expr(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e7(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.rule8_close(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) e9(pos int) ([]*parser.Node, int, bool) {
+	start := pos
+	var ns []*parser.Node
+	if t, r, ok := p.e8(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if len(ns) == 0 {
+		s := p.startOf(start)
+		return []*parser.Node{{Type: "select", Start: s, End: s}}, pos, true
+	}
+	return []*parser.Node{{Type: "select", Start: ns[0].Start, End: ns[len(ns)-1].End, Elts: dropPhantoms(ns)}}, pos, true
+}
+
+func (p *Parser) rule2_select(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "select")
+	ns, r, ok := p.e9(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e10(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c2, pos)
+	}
+	if p.token(pos, "WHERE") {
+		return nil, pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e11(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.rule1_expr(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e12(pos int) ([]*parser.Node, int, bool) {
+	ns, pos, ok := p.e11(pos)
+	if !ok {
+		return nil, 0, false
+	}
+	for pos < len(p.toks) {
+		t, r, ok := p.e11(pos)
+		if !ok {
+			break
+		}
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) e13(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.rule7_open(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e10(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.rule1_expr(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e12(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.rule8_close(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) e14(pos int) ([]*parser.Node, int, bool) {
+	start := pos
+	var ns []*parser.Node
+	if t, r, ok := p.e13(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if len(ns) == 0 {
+		s := p.startOf(start)
+		return []*parser.Node{{Type: "where", Start: s, End: s}}, pos, true
+	}
+	return []*parser.Node{{Type: "where", Start: ns[0].Start, End: ns[len(ns)-1].End, Elts: dropPhantoms(ns)}}, pos, true
+}
+
+func (p *Parser) rule3_where(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "where")
+	ns, r, ok := p.e14(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e15(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c3, pos)
+	}
+	if p.token(pos, "FROM") {
+		return nil, pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e16(pos int) ([]*parser.Node, int, bool) {
+	start := pos
+	var ns []*parser.Node
+	if t, r, ok := p.rule7_open(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e15(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.rule1_expr(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.rule8_close(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if len(ns) == 0 {
+		s := p.startOf(start)
+		return []*parser.Node{{Type: "from", Start: s, End: s}}, pos, true
+	}
+	return []*parser.Node{{Type: "from", Start: ns[0].Start, End: ns[len(ns)-1].End, Elts: dropPhantoms(ns)}}, pos, true
+}
+
+func (p *Parser) rule4_from(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "from")
+	ns, r, ok := p.e16(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e17(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c4, pos)
+	}
+	if p.token(pos, ".") {
+		return nil, pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e18(pos int) ([]*parser.Node, int, bool) {
+	start := pos
+	var ns []*parser.Node
+	if t, r, ok := p.rule7_open(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e17(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.rule9_nonParens(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.rule9_nonParens(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.rule8_close(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if len(ns) == 0 {
+		s := p.startOf(start)
+		return []*parser.Node{{Type: "field", Start: s, End: s}}, pos, true
+	}
+	return []*parser.Node{{Type: "field", Start: ns[0].Start, End: ns[len(ns)-1].End, Elts: dropPhantoms(ns)}}, pos, true
+}
+
+func (p *Parser) rule5_field(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "field")
+	ns, r, ok := p.e18(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e19(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c5, pos)
+	}
+	if p.token(pos, "SELECT") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e20(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c6, pos)
+	}
+	if p.token(pos, "WHERE") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e21(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c7, pos)
+	}
+	if p.token(pos, "FROM") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e22(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c8, pos)
+	}
+	if p.token(pos, ".") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e23(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.e19(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.e20(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.e21(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.e22(pos); ok {
+		return t, r, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e24(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e23(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e25(pos int) ([]*parser.Node, int, bool) {
+	if pos >= len(p.toks) {
+		return nil, 0, false
+	}
+	if _, _, ok := p.e24(pos); ok {
+		return nil, 0, false
+	}
+	return p.toks[pos : pos+1], pos + 1, true
+}
+
+func (p *Parser) e26(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.rule1_expr(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e27(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	for pos < len(p.toks) {
+		t, r, ok := p.e26(pos)
+		if !ok {
+			break
+		}
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) e28(pos int) ([]*parser.Node, int, bool) {
+	start := pos
+	var ns []*parser.Node
+	if t, r, ok := p.rule7_open(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e25(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e27(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.rule8_close(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if len(ns) == 0 {
+		s := p.startOf(start)
+		return []*parser.Node{{Type: "func", Start: s, End: s}}, pos, true
+	}
+	return []*parser.Node{{Type: "func", Start: ns[0].Start, End: ns[len(ns)-1].End, Elts: dropPhantoms(ns)}}, pos, true
+}
+
+func (p *Parser) rule6_func(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "func")
+	ns, r, ok := p.e28(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e29(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c9, pos)
+	}
+	if p.token(pos, "(") {
+		return phantom(p.toks[pos : pos+1]), pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e30(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c10, pos)
+	}
+	if p.token(pos, "[") {
+		return phantom(p.toks[pos : pos+1]), pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e31(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.e29(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.e30(pos); ok {
+		return t, r, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) rule7_open(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "open")
+	ns, r, ok := p.e31(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e32(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c11, pos)
+	}
+	if p.token(pos, ")") {
+		return phantom(p.toks[pos : pos+1]), pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e33(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c12, pos)
+	}
+	if p.token(pos, "]") {
+		return phantom(p.toks[pos : pos+1]), pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e34(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.e32(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.e33(pos); ok {
+		return t, r, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) rule8_close(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "close")
+	ns, r, ok := p.e34(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e35(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.rule7_open(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e36(pos int) ([]*parser.Node, int, bool) {
+	if pos >= len(p.toks) {
+		return nil, 0, false
+	}
+	if _, _, ok := p.e35(pos); ok {
+		return nil, 0, false
+	}
+	return p.toks[pos : pos+1], pos + 1, true
+}
+
+func (p *Parser) e37(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.rule8_close(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e38(pos int) ([]*parser.Node, int, bool) {
+	if pos >= len(p.toks) {
+		return nil, 0, false
+	}
+	if _, _, ok := p.e37(pos); ok {
+		return nil, 0, false
+	}
+	return p.toks[pos : pos+1], pos + 1, true
+}
+
+func (p *Parser) e39(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	r := pos
+	if t, rr, ok := p.e36(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, r = t, rr
+	}
+	if t, rr, ok := p.e38(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, r = t, rr
+	}
+	return ns, r, true
+}
+
+func (p *Parser) rule9_nonParens(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "nonParens")
+	ns, r, ok := p.e39(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e40(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(parser.P["$pred"](p.preds["id"]), pos)
+	}
+	if pos < len(p.toks) && p.preds["id"](p.toks[pos]) {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e41(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c13, pos)
+	}
+	if p.token(pos, ",") {
+		return nil, pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e42(pos int) ([]*parser.Node, int, bool) {
+	ns, pos, ok := p.e40(pos)
+	if !ok {
+		return nil, 0, false
+	}
+	for pos < len(p.toks) {
+		ts, r, ok := p.e41(pos)
+		if !ok {
+			break
+		}
+		tc, r, ok := p.e40(r)
+		if !ok {
+			break
+		}
+		ns, pos = append(append(ns, ts...), tc...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) rule10_list(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "list")
+	ns, r, ok := p.e42(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e43(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c14, pos)
+	}
+	if p.token(pos, "x") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e44(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c15, pos)
+	}
+	if p.token(pos, "y") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e45(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e43(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e44(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e46(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.e45(pos); ok {
+		return t, r, true
+	}
+	return nil, pos, true
+}
+
+func (p *Parser) e47(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c16, pos)
+	}
+	if p.token(pos, "z") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e48(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e47(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return nil, pos, true
+}
+
+func (p *Parser) e49(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c17, pos)
+	}
+	if p.token(pos, "w") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e50(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e49(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return phantom(dropPhantoms(ns)), pos, true
+}
+
+func (p *Parser) e51(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c18, pos)
+	}
+	if p.token(pos, "q") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e52(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e51(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e53(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	for pos < len(p.toks) {
+		t, r, ok := p.e51(pos)
+		if !ok {
+			break
+		}
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) e54(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c19, pos)
+	}
+	if p.token(pos, "k") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e55(pos int) ([]*parser.Node, int, bool) {
+	if pos >= len(p.toks) {
+		return nil, 0, false
+	}
+	if _, _, ok := p.e54(pos); ok {
+		return nil, 0, false
+	}
+	return p.toks[pos : pos+1], pos + 1, true
+}
+
+func (p *Parser) e56(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c20, pos)
+	}
+	if p.token(pos, "m") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e57(pos int) ([]*parser.Node, int, bool) {
+	if _, r, ok := p.e56(pos); ok {
+		return nil, r, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e58(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c21, pos)
+	}
+	if p.token(pos, "n") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e59(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e58(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e60(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e46(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e48(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e50(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e53(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e55(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e57(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e59(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) rule11_opt(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "opt")
+	ns, r, ok := p.e60(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e61(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(parser.P["$pred"](p.preds["number"]), pos)
+	}
+	if pos < len(p.toks) && p.preds["number"](p.toks[pos]) {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) rule12_num(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "num")
+	ns, r, ok := p.e61(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e62(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c22, pos)
+	}
+	if p.token(pos, "-") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e63(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	for pos < len(p.toks) {
+		t, r, ok := p.e62(pos)
+		if !ok {
+			break
+		}
+		ns, pos = append(ns, t...), r
+	}
+	if len(ns) == 0 {
+		return nil, 0, false
+	}
+	t, r, ok := p.rule12_num(pos)
+	if !ok {
+		return nil, 0, false
+	}
+	return []*parser.Node{parser.MakePrefix("neg", dropPhantoms(append(ns, t...)))}, r, true
+}
+
+func (p *Parser) e64(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.e63(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.rule12_num(pos); ok {
+		return t, r, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e65(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c23, pos)
+	}
+	if p.token(pos, "+") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e66(pos int) ([]*parser.Node, int, bool) {
+	ns, pos, ok := p.e64(pos)
+	if !ok {
+		return nil, 0, false
+	}
+	n := len(ns)
+	for {
+		to, r, ok := p.e65(pos)
+		if !ok {
+			break
+		}
+		tc, r, ok := p.e64(r)
+		if !ok {
+			break
+		}
+		ns, pos = append(append(ns, to...), tc...), r
+	}
+	if len(ns) == n {
+		return nil, 0, false
+	}
+	return []*parser.Node{parser.MakeInfix("add", ns, "left")}, pos, true
+}
+
+func (p *Parser) rule13_calc(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "calc")
+	ns, r, ok := p.e66(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e67(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c24, pos)
+	}
+	if p.token(pos, "++") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e68(pos int) ([]*parser.Node, int, bool) {
+	ns, pos, ok := p.rule12_num(pos)
+	if !ok {
+		return nil, 0, false
+	}
+	n := len(ns)
+	for pos < len(p.toks) {
+		t, r, ok := p.e67(pos)
+		if !ok {
+			break
+		}
+		ns, pos = append(ns, t...), r
+	}
+	if len(ns) == n {
+		return nil, 0, false
+	}
+	return []*parser.Node{parser.MakePostfix("inc", dropPhantoms(ns))}, pos, true
+}
+
+func (p *Parser) e69(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.e68(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.rule12_num(pos); ok {
+		return t, r, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e70(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c25, pos)
+	}
+	if p.token(pos, "^") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e71(pos int) ([]*parser.Node, int, bool) {
+	ns, pos, ok := p.e69(pos)
+	if !ok {
+		return nil, 0, false
+	}
+	n := len(ns)
+	for {
+		to, r, ok := p.e70(pos)
+		if !ok {
+			break
+		}
+		tc, r, ok := p.e69(r)
+		if !ok {
+			break
+		}
+		ns, pos = append(append(ns, to...), tc...), r
+	}
+	if len(ns) == n {
+		return nil, 0, false
+	}
+	return []*parser.Node{parser.MakeInfix("pow", ns, "right")}, pos, true
+}
+
+func (p *Parser) rule14_calcr(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "calcr")
+	ns, r, ok := p.e71(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e72(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c26, pos)
+	}
+	if p.token(pos, "<") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e73(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c27, pos)
+	}
+	if p.token(pos, ">") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e74(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.e72(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.e73(pos); ok {
+		return t, r, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e75(pos int) ([]*parser.Node, int, bool) {
+	ns, pos, ok := p.rule12_num(pos)
+	if !ok {
+		return nil, 0, false
+	}
+	n := len(ns)
+	for {
+		to, r, ok := p.e74(pos)
+		if !ok {
+			break
+		}
+		tc, r, ok := p.rule12_num(r)
+		if !ok {
+			break
+		}
+		ns, pos = append(append(ns, to...), tc...), r
+	}
+	if len(ns) == n {
+		return nil, 0, false
+	}
+	if len(ns) > 3 {
+		panic(parser.NonAssociative(ns[3]))
+	}
+	return []*parser.Node{parser.MakeInfix("rel", ns, "none")}, pos, true
+}
+
+func (p *Parser) rule15_rel(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "rel")
+	ns, r, ok := p.e75(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e76(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c28, pos)
+	}
+	if p.token(pos, ";") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e77(pos int) ([]*parser.Node, int, bool) {
+	t, r, ok, err := p.try((*Parser).rule17_stmt, pos)
+	if ok {
+		return t, r, true
+	}
+	n := 0
+	for pos+n < len(p.toks) {
+		if _, _, ok := p.e76(pos + n); ok {
+			break
+		}
+		n++
+	}
+	if n == 0 && err != nil {
+		panic(err)
+	} else if n == 0 {
+		return nil, 0, false
+	}
+	return []*parser.Node{parser.SkippedNode(p.toks[pos:], n, err)}, pos + n, true
+}
+
+func (p *Parser) e78(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c29, pos)
+	}
+	if p.token(pos, ";") {
+		return nil, pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e79(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e77(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e78(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) e80(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e79(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e81(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	for pos < len(p.toks) {
+		t, r, ok := p.e80(pos)
+		if !ok {
+			break
+		}
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) rule16_stmts(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "stmts")
+	ns, r, ok := p.e81(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e82(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c30, pos)
+	}
+	if p.token(pos, "let") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e83(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(parser.P["$pred"](p.preds["id"]), pos)
+	}
+	if pos < len(p.toks) && p.preds["id"](p.toks[pos]) {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e84(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e83(pos); !ok {
+		panic(parser.Unexpected(p.toks[pos:]))
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) e85(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e82(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e84(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) rule17_stmt(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "stmt")
+	ns, r, ok := p.e85(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e86(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c31, pos)
+	}
+	if p.token(pos, "LIMIT") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e87(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c32, pos)
+	}
+	if p.token(pos, "0") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e88(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c33, pos)
+	}
+	if p.token(pos, "10") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e89(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.e87(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.e88(pos); ok {
+		return t, r, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e90(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e86(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e89(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.rule12_num(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) rule18_lim(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "lim")
+	ns, r, ok := p.e90(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e91(pos int) ([]*parser.Node, int, bool) {
+	return p.apply(parser.Spaces, pos)
+}
+
+func (p *Parser) e92(pos int) ([]*parser.Node, int, bool) {
+	return p.apply(c34, pos)
+}
+
+func (p *Parser) e93(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(parser.P["$pred"](p.preds["str"]), pos)
+	}
+	if pos < len(p.toks) && p.preds["str"](p.toks[pos]) {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e94(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.rule20_word(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.rule21_digits(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.e92(pos); ok {
+		return t, r, true
+	}
+	if t, r, ok := p.e93(pos); ok {
+		return t, r, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e95(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e94(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e96(pos int) ([]*parser.Node, int, bool) {
+	ns, pos, ok := p.e95(pos)
+	if !ok {
+		return nil, 0, false
+	}
+	for pos < len(p.toks) {
+		t, r, ok := p.e95(pos)
+		if !ok {
+			break
+		}
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) e97(pos int) ([]*parser.Node, int, bool) {
+	if pos < len(p.toks) && p.toks[pos].Type == "eof" {
+		return nil, pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e98(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e91(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e96(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e97(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) rule19_chars(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "chars")
+	ns, r, ok := p.e98(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e99(pos int) ([]*parser.Node, int, bool) {
+	return p.apply(c35, pos)
+}
+
+func (p *Parser) e100(pos int) ([]*parser.Node, int, bool) {
+	start := pos
+	var ns []*parser.Node
+	if t, r, ok := p.e99(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	tok := parser.Span("word", p.toks[start:], pos-start)
+	_, pos, _ = p.apply(parser.Spaces, pos)
+	return []*parser.Node{tok}, pos, true
+}
+
+func (p *Parser) rule20_word(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "word")
+	ns, r, ok := p.e100(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e101(pos int) ([]*parser.Node, int, bool) {
+	return p.apply(c36, pos)
+}
+
+func (p *Parser) e102(pos int) ([]*parser.Node, int, bool) {
+	return p.apply(c37, pos)
+}
+
+func (p *Parser) e103(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e102(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e104(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	for pos < len(p.toks) {
+		t, r, ok := p.e103(pos)
+		if !ok {
+			break
+		}
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) e105(pos int) ([]*parser.Node, int, bool) {
+	start := pos
+	var ns []*parser.Node
+	if t, r, ok := p.e101(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e104(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	tok := parser.Span("digits", p.toks[start:], pos-start)
+	_, pos, _ = p.apply(parser.Spaces, pos)
+	return []*parser.Node{tok}, pos, true
+}
+
+func (p *Parser) rule21_digits(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "digits")
+	ns, r, ok := p.e105(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e106(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(parser.P["$pred"](p.preds["id"]), pos)
+	}
+	if pos < len(p.toks) && p.preds["id"](p.toks[pos]) {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e107(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c38, pos)
+	}
+	if p.token(pos, "=") {
+		return nil, pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e108(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c39, pos)
+	}
+	if pos < len(p.toks) && p.toks[pos].Type == "number" {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e109(pos int) ([]*parser.Node, int, bool) {
+	return p.apply(c40, pos)
+}
+
+func (p *Parser) e110(pos int) ([]*parser.Node, int, bool) {
+	start := pos
+	var ns []*parser.Node
+	if t, r, ok := p.e109(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	tok := parser.Span("rest", p.toks[start:], pos-start)
+	_, pos, _ = p.apply(parser.Spaces, pos)
+	return []*parser.Node{tok}, pos, true
+}
+
+func (p *Parser) e111(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c41, pos)
+	}
+	if p.token(pos, ";") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e112(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e106(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e107(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e108(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e110(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e111(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) rule22_assign(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "assign")
+	ns, r, ok := p.e112(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e113(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c42, pos)
+	}
+	if p.token(pos, "begin") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e114(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(parser.P["$pred"](p.preds["id"]), pos)
+	}
+	if pos < len(p.toks) && p.preds["id"](p.toks[pos]) {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e115(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c43, pos)
+	}
+	if p.token(pos, ",") {
+		return nil, pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e116(pos int) ([]*parser.Node, int, bool) {
+	ns, r, ok := p.e114(pos)
+	if !ok {
+		return nil, pos, true
+	}
+	pos = r
+	for pos < len(p.toks) {
+		ts, r, ok := p.e115(pos)
+		if !ok {
+			break
+		}
+		tc, r, ok := p.e114(r)
+		if !ok || r == pos {
+			break
+		}
+		ns, pos = append(append(ns, ts...), tc...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e117(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c44, pos)
+	}
+	if p.token(pos, "end") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e118(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e113(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e116(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e117(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) rule23_sepby(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "sepby")
+	ns, r, ok := p.e118(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e119(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(parser.P["$pred"](p.preds["id"]), pos)
+	}
+	if pos < len(p.toks) && p.preds["id"](p.toks[pos]) {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e120(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c45, pos)
+	}
+	if p.token(pos, ",") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e121(pos int) ([]*parser.Node, int, bool) {
+	ns, r, ok := p.e119(pos)
+	if !ok {
+		return nil, pos, true
+	}
+	pos = r
+	for pos < len(p.toks) {
+		ts, r, ok := p.e120(pos)
+		if !ok {
+			break
+		}
+		tc, r, ok := p.e119(r)
+		if !ok || r == pos {
+			break
+		}
+		ns, pos = append(append(ns, ts...), tc...), r
+	}
+	if t, r, ok := p.e120(pos); ok {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) rule24_sepend(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "sepend")
+	ns, r, ok := p.e121(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e122(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c46, pos)
+	}
+	if p.token(pos, "x") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e123(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e122(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e124(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.e123(pos); ok {
+		return t, r, true
+	}
+	return nil, pos, true
+}
+
+func (p *Parser) e125(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c47, pos)
+	}
+	if p.token(pos, ",") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e126(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e125(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) e127(pos int) ([]*parser.Node, int, bool) {
+	if t, r, ok := p.e126(pos); ok {
+		return t, r, true
+	}
+	return nil, pos, true
+}
+
+func (p *Parser) e128(pos int) ([]*parser.Node, int, bool) {
+	ns, r, ok := p.e124(pos)
+	if !ok {
+		return nil, pos, true
+	}
+	pos = r
+	for pos < len(p.toks) {
+		ts, r, ok := p.e127(pos)
+		if !ok {
+			break
+		}
+		tc, r, ok := p.e124(r)
+		if !ok || r == pos {
+			break
+		}
+		ns, pos = append(append(ns, ts...), tc...), r
+	}
+	return dropPhantoms(ns), pos, true
+}
+
+func (p *Parser) rule25_empty(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "empty")
+	ns, r, ok := p.e128(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e129(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(parser.P["$pred"](p.preds["token"]), pos)
+	}
+	if pos < len(p.toks) && p.preds["token"](p.toks[pos]) {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e130(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c48, pos)
+	}
+	if p.token(pos, ";") {
+		return nil, pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e131(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	for {
+		if _, r, ok := p.e130(pos); ok {
+			return ns, r, true
+		}
+		t, r, ok := p.e129(pos)
+		if !ok || r == pos {
+			return nil, 0, false
+		}
+		ns, pos = append(ns, t...), r
+	}
+}
+
+func (p *Parser) rule26_till(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "till")
+	ns, r, ok := p.e131(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
+
+func (p *Parser) e132(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c49, pos)
+	}
+	if p.token(pos, "a") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e133(pos int) ([]*parser.Node, int, bool) {
+	if _, _, ok := p.e132(pos); !ok {
+		return nil, 0, false
+	}
+	return nil, pos, true
+}
+
+func (p *Parser) e134(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c50, pos)
+	}
+	if p.token(pos, "a") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e135(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(c51, pos)
+	}
+	if p.token(pos, "b") {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e136(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e134(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e135(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) e137(pos int) ([]*parser.Node, int, bool) {
+	if _, _, ok := p.e136(pos); ok {
+		return nil, 0, false
+	}
+	return nil, pos, true
+}
+
+func (p *Parser) e138(pos int) ([]*parser.Node, int, bool) {
+	if p.isRune(pos) {
+		return p.apply(parser.P["$pred"](p.preds["id"]), pos)
+	}
+	if pos < len(p.toks) && p.preds["id"](p.toks[pos]) {
+		return p.toks[pos : pos+1], pos + 1, true
+	}
+	return nil, 0, false
+}
+
+func (p *Parser) e139(pos int) ([]*parser.Node, int, bool) {
+	var ns []*parser.Node
+	if t, r, ok := p.e133(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e137(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	if t, r, ok := p.e138(pos); !ok {
+		return nil, 0, false
+	} else {
+		ns, pos = append(ns, t...), r
+	}
+	return ns, pos, true
+}
+
+func (p *Parser) rule27_peek(pos int) ([]*parser.Node, int, bool) {
+	p.rules = append(p.rules, "peek")
+	ns, r, ok := p.e139(pos)
+	p.rules = p.rules[:len(p.rules)-1]
+	return ns, r, ok
+}
@@ -0,0 +1,143 @@
+package example
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/Aiyane/parsec-go/parser"
+)
+
+func dump(ns []*parser.Node) string {
+	if ns == nil {
+		return "<nil>"
+	}
+	s := ""
+	for _, n := range ns {
+		if n.Elts == nil {
+			s += fmt.Sprintf("%s:%s@%d ", n.Type, n.Text, n.Start)
+		} else if parser.IsError(n) {
+			s += fmt.Sprintf("(%s@%d-%d %q %s) ", n.Type, n.Start, n.End, n.Text, dump(n.Elts))
+		} else {
+			s += fmt.Sprintf("(%s@%d-%d %s) ", n.Type, n.Start, n.End, dump(n.Elts))
+		}
+	}
+	return s
+}
+
+// 生成的解析器与 LoadGrammar 解释执行的结果相同
+func TestSameAsInterpreter(t *testing.T) {
+	src, err := os.ReadFile("example.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := parser.LoadGrammar(string(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetDelims("(", ")", "[", "]", ",")
+	parser.SetLineComment("//") // ; 是 stmts 中的分隔符
+	for _, c := range []struct{ rule, src string }{
+		{"expr", `(SELECT (WHERE (FROM t) (= (. a b) 1)) (. a x) (f 1 2) 10)`},
+		{"expr", `(f (g) x`},
+		{"expr", `)`},
+		{"list", `a , b , c d`},
+		{"opt", `x y z w q q j m n`},
+		{"opt", `z w j m n`},
+		{"opt", `z w k`},
+		{"calc", `- 1 + 2 + - - 3`},
+		{"calc", `1 +`},
+		{"calcr", `1 ++ ^ 2 ^ 3 ++ ++`},
+		{"rel", `1 < 2`},
+		{"rel", `1 > 2 x`},
+		{"stmts", `let a ; x y ; let b ;`},
+		{"stmts", `let a ; ; let b`},
+		{"stmts", `let a ; let 1 2 ; let b ;`},
+		{"lim", `LIMIT 10 5`},
+		{"lim", `LIMIT 1 5`},
+		{"sepby", `begin a , b , c end`},
+		{"sepby", `begin end`},
+		{"sepby", `begin a , end`},
+		{"sepend", `a , b ,`},
+		{"sepend", `a b`},
+		{"empty", `y`},
+		{"empty", `x , x x`},
+		{"till", `a b ; c`},
+		{"till", `a b c`},
+		{"peek", `a c`},
+		{"peek", `a b`},
+		{"peek", `b`},
+		{"nothing", `a`},
+	} {
+		toks := parser.Scan(c.src)
+		var want, wantRest []*parser.Node
+		if r := g.Get(c.rule); r != nil {
+			want, wantRest = parser.Eval(r, toks)
+		}
+		got, rest := Parse(c.rule, toks)
+		if dump(got) != dump(want) || len(rest) != len(wantRest) {
+			t.Errorf("%s %q:\ngenerated   %s (%d left)\ninterpreted %s (%d left)", c.rule, c.src, dump(got), len(rest), dump(want), len(wantRest))
+		}
+	}
+}
+
+// @^ 剪枝后的错误与 EvalErr 相同
+func TestParseErr(t *testing.T) {
+	src, err := os.ReadFile("example.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := parser.LoadGrammar(string(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetDelims("(", ")", "[", "]", ",")
+	for _, c := range []struct{ rule, src string }{
+		{"stmt", `let 1`},
+		{"stmt", `let`},
+		{"stmt", `let a`},
+		{"rel", `1 < 2 > 3`},
+	} {
+		toks := parser.Scan(c.src)
+		want, _, wantErr := parser.EvalErr(g.Get(c.rule), toks)
+		got, _, err := New(toks, nil).ParseErr(c.rule)
+		if dump(got) != dump(want) || fmt.Sprint(err) != fmt.Sprint(wantErr) {
+			t.Errorf("%s %q:\ngenerated   %s %v\ninterpreted %s %v", c.rule, c.src, dump(got), err, dump(want), wantErr)
+		}
+	}
+	if _, _, err := New(parser.Scan(`1 < 2 > 3`), nil).ParseErr("rel"); err == nil || err.Error() != `6: rel: non-associative operator ">" cannot be chained` {
+		t.Errorf("error = %v", err)
+	}
+	if _, _, err := New(parser.Scan(`let 1`), nil).ParseErr("stmt"); err == nil || err.(*parser.ParseError).Rule != "stmt" {
+		t.Errorf("error = %v, want rule stmt", err)
+	}
+}
+
+// 字符输入上与解释执行的结果相同
+func TestSameAsInterpreterChars(t *testing.T) {
+	src, err := os.ReadFile("example.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := parser.LoadGrammar(string(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parser.SetDelims("(", ")", "[", "]", ",")
+	for _, c := range []struct{ rule, src string }{
+		{"chars", ` a-b -> 12 "s t" x`},
+		{"chars", `a-b ->`},
+		{"chars", `a - b`},
+		{"chars", `A`},
+		{"assign", `x = 10 rest of it;`},
+		{"assign", `x = y;`},
+		{"list", `a, b ,c d`},
+	} {
+		toks := parser.ScanChars(c.src)
+		want, wantRest := parser.Eval(g.Get(c.rule), toks)
+		got, rest := Parse(c.rule, toks)
+		if dump(got) != dump(want) || len(rest) != len(wantRest) {
+			t.Errorf("%s %q:\ngenerated   %s (%d left)\ninterpreted %s (%d left)", c.rule, c.src, dump(got), len(rest), dump(want), len(wantRest))
+		}
+	}
+}
@@ -0,0 +1,638 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+
+	"github.com/Aiyane/parsec-go/parser"
+)
+
+// 把文法生成为独立的递归下降解析器.
+// 生成的代码按 token 下标前进, 不构造闭包, 产生的 Node 树与 Eval 相同.
+// g 可以来自 LoadGrammar (文法文本), 也可以来自 DeclareGrammar (Go 代码中声明);
+// 用 Define 定义的规则只有闭包, 没有规则体, 无法生成.
+func Generate(g *parser.Grammar, pkg string) ([]byte, error) {
+	w := &writer{g: g, rules: make(map[string]string)}
+	for i, name := range g.Names {
+		w.rules[name] = fmt.Sprintf("rule%d%s", i, ident(name))
+	}
+	for _, name := range g.Names {
+		def, ok := g.Defs[name]
+		if !ok {
+			return nil, fmt.Errorf("gen: rule %q has no grammar definition", name)
+		}
+		body, err := w.expr(def)
+		if err != nil {
+			return nil, fmt.Errorf("gen: rule %q: %v", name, err)
+		}
+		w.fn(w.rules[name], fmt.Sprintf("p.rules = append(p.rules, %q)\nns, r, ok := p.%s(pos)\np.rules = p.rules[:len(p.rules)-1]\nreturn ns, r, ok", name, body))
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, header, pkg)
+	fmt.Fprintf(&out, "var rules = map[string]func(*Parser, int) ([]*parser.Node, int, bool){\n")
+	for _, name := range g.Names {
+		fmt.Fprintf(&out, "%q: (*Parser).%s,\n", name, w.rules[name])
+	}
+	fmt.Fprintf(&out, "}\n\nconst memoSize = %d\n\n", w.memo)
+	if w.vars.Len() > 0 {
+		fmt.Fprintf(&out, "var (\n%s)\n\n", w.vars.Bytes())
+	}
+	out.Write(w.body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("gen: %v", err)
+	}
+	return src, nil
+}
+
+const header = `// Code generated by parsec-gen. DO NOT EDIT.
+
+package %s
+
+import "github.com/Aiyane/parsec-go/parser"
+
+type memoEntry struct {
+	done  bool
+	ok    bool
+	nodes []*parser.Node
+	pos   int
+}
+
+type Parser struct {
+	toks  []*parser.Node
+	preds map[string]func(*parser.Node) bool
+	memo  [][]memoEntry
+	rules []string // 正在解析的规则, 用于 ParseError 的规则名
+}
+
+// preds 为 nil 时使用 parser.Preds
+func New(toks []*parser.Node, preds map[string]func(*parser.Node) bool) *Parser {
+	if preds == nil {
+		preds = parser.Preds
+	}
+	return &Parser{toks: toks, preds: preds, memo: make([][]memoEntry, memoSize)}
+}
+
+// 从规则 rule 开始解析, 失败时返回 nil
+func Parse(rule string, toks []*parser.Node) ([]*parser.Node, []*parser.Node) {
+	return New(toks, nil).Parse(rule)
+}
+
+func (p *Parser) Parse(rule string) ([]*parser.Node, []*parser.Node) {
+	ns, rest, _ := p.ParseErr(rule)
+	return ns, rest
+}
+
+// 同 Parse, @^ 剪枝后的失败作为 error 返回
+func (p *Parser) ParseErr(rule string) ([]*parser.Node, []*parser.Node, error) {
+	f, ok := rules[rule]
+	if !ok {
+		return nil, nil, nil
+	}
+	ns, pos, ok, err := p.try(f, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, nil
+	}
+	if ns == nil {
+		ns = make([]*parser.Node, 0)
+	}
+	return ns, p.toks[pos:], nil
+}
+
+// 运行 f, 接住其中 @^ 抛出的 ParseError, 规则名取出错时最内层的规则
+func (p *Parser) try(f func(*Parser, int) ([]*parser.Node, int, bool), pos int) (ns []*parser.Node, r int, ok bool, err *parser.ParseError) {
+	depth := len(p.rules)
+	defer func() {
+		if x := recover(); x != nil {
+			e, isErr := x.(*parser.ParseError)
+			if !isErr {
+				panic(x)
+			}
+			if e.Rule == "" && len(p.rules) > 0 {
+				e.Rule = p.rules[len(p.rules)-1]
+			}
+			p.rules = p.rules[:depth]
+			ns, r, ok, err = nil, 0, false, e
+		}
+	}()
+	ns, r, ok = f(p, pos)
+	return ns, r, ok, nil
+}
+
+func (p *Parser) cached(id, pos int, f func(*Parser, int) ([]*parser.Node, int, bool)) ([]*parser.Node, int, bool) {
+	if p.memo[id] == nil {
+		p.memo[id] = make([]memoEntry, len(p.toks)+1)
+	}
+	if e := p.memo[id][pos]; e.done {
+		return e.nodes, e.pos, e.ok
+	}
+	ns, r, ok := f(p, pos)
+	p.memo[id][pos] = memoEntry{done: true, ok: ok, nodes: ns, pos: r}
+	return ns, r, ok
+}
+
+// 在 pos 处运行解释执行的组合子, 用于字符级的运算符
+func (p *Parser) apply(c parser.Combinator, pos int) ([]*parser.Node, int, bool) {
+	t, r := c()(p.toks[pos:], nil, nil)
+	if t == nil {
+		return nil, 0, false
+	}
+	return t, len(p.toks) - len(r), true
+}
+
+func (p *Parser) isRune(pos int) bool {
+	return pos < len(p.toks) && parser.IsRune(p.toks[pos])
+}
+
+func (p *Parser) token(pos int, s string) bool {
+	return pos < len(p.toks) && (parser.IsTokenType(p.toks[pos]) || parser.IsNumber(p.toks[pos])) && p.toks[pos].Text == s
+}
+
+func (p *Parser) startOf(pos int) int {
+	if pos < len(p.toks) {
+		return p.toks[pos].Start
+	}
+	if pos > 0 {
+		return p.toks[pos-1].End
+	}
+	return 0
+}
+
+func dropPhantoms(ns []*parser.Node) []*parser.Node {
+	ret := make([]*parser.Node, 0, len(ns))
+	for _, n := range ns {
+		if !parser.IsPhantom(n) {
+			ret = append(ret, n)
+		}
+	}
+	return ret
+}
+
+func phantom(ns []*parser.Node) []*parser.Node {
+	if len(ns) == 0 {
+		return nil
+	}
+	return []*parser.Node{{Type: parser.PhantomType, Start: ns[0].Start, End: ns[len(ns)-1].End}}
+}
+
+`
+
+type writer struct {
+	g     *parser.Grammar
+	rules map[string]string
+	body  bytes.Buffer
+	vars  bytes.Buffer // 字符级运算符使用的组合子
+	n     int
+	memo  int
+	nvar  int
+}
+
+// 规则名中可以用作 Go 标识符的部分, 仅为生成代码的可读性
+func ident(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return "_" + b.String()
+}
+
+func (w *writer) fn(name, body string) {
+	fmt.Fprintf(&w.body, "func (p *Parser) %s(pos int) ([]*parser.Node, int, bool) {\n%s\n}\n\n", name, body)
+}
+
+// 生成一个包级的组合子变量, 返回变量名
+func (w *writer) combinator(expr string) string {
+	w.nvar++
+	name := fmt.Sprintf("c%d", w.nvar)
+	fmt.Fprintf(&w.vars, "%s = %s\n", name, expr)
+	return name
+}
+
+func (w *writer) next() string {
+	w.n++
+	return fmt.Sprintf("e%d", w.n)
+}
+
+func (w *writer) exprs(ns []*parser.Node) ([]string, error) {
+	fs := make([]string, 0, len(ns))
+	for _, n := range ns {
+		f, err := w.expr(n)
+		if err != nil {
+			return nil, err
+		}
+		fs = append(fs, f)
+	}
+	return fs, nil
+}
+
+// 为 n 生成一个方法, 返回方法名
+func (w *writer) expr(n *parser.Node) (string, error) {
+	switch {
+	case parser.IsStrType(n):
+		return w.token(n.Text, ""), nil
+	case parser.IsTokenType(n):
+		switch n.Text {
+		case "$fail":
+			f := w.next()
+			w.fn(f, "return nil, 0, false")
+			return f, nil
+		case "$none":
+			f := w.next()
+			w.fn(f, "return nil, pos, true")
+			return f, nil
+		case "$spaces":
+			f := w.next()
+			w.fn(f, "return p.apply(parser.Spaces, pos)")
+			return f, nil
+		case "$eof":
+			f := w.next()
+			w.fn(f, fmt.Sprintf("if pos < len(p.toks) && p.toks[pos].Type == %q {\nreturn nil, pos + 1, true\n}\nreturn nil, 0, false", parser.EofType))
+			return f, nil
+		}
+		if rule, ok := w.rules[n.Text]; ok {
+			return rule, nil
+		}
+		return "", fmt.Errorf("%d: undefined rule %q", n.Start, n.Text)
+	case n.Type == "sexp":
+		elts := filter(n.Elts)
+		if len(elts) == 0 || !parser.IsTokenType(elts[0]) {
+			return "", fmt.Errorf("%d: expected operator", n.Start)
+		}
+		return w.list(elts[0].Text, elts[1:], n.Start)
+	}
+	return "", fmt.Errorf("%d: unexpected %s %q", n.Start, n.Type, n.Text)
+}
+
+func filter(ns []*parser.Node) []*parser.Node {
+	ret := make([]*parser.Node, 0, len(ns))
+	for _, n := range ns {
+		if !parser.IsComment(n) {
+			ret = append(ret, n)
+		}
+	}
+	return ret
+}
+
+func atom(n *parser.Node) (string, error) {
+	if parser.IsTokenType(n) || parser.IsStrType(n) {
+		return n.Text, nil
+	}
+	return "", fmt.Errorf("%d: expected atom", n.Start)
+}
+
+// $$, @_, @~, 字符输入上使用解释执行的组合子
+func (w *writer) token(s, mode string) string {
+	f := w.next()
+	ret, op := "p.toks[pos : pos+1]", "$$"
+	switch mode {
+	case "glob":
+		ret, op = "nil", "@_"
+	case "phantom":
+		ret, op = "phantom(p.toks[pos : pos+1])", "@~"
+	}
+	c := w.combinator(fmt.Sprintf("parser.S[%q](%q)", op, s))
+	w.fn(f, fmt.Sprintf("if p.isRune(pos) {\nreturn p.apply(%s, pos)\n}\nif p.token(pos, %q) {\nreturn %s, pos + 1, true\n}\nreturn nil, 0, false", c, s, ret))
+	return f
+}
+
+// 顺序匹配 fs, 结果拼接在 ns 中
+func seq(fs []string) string {
+	var b strings.Builder
+	b.WriteString("var ns []*parser.Node\n")
+	for _, f := range fs {
+		fmt.Fprintf(&b, "if t, r, ok := p.%s(pos); !ok {\nreturn nil, 0, false\n} else {\nns, pos = append(ns, t...), r\n}\n", f)
+	}
+	return b.String()
+}
+
+func (w *writer) list(op string, args []*parser.Node, at int) (string, error) {
+	arity := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("%d: %s expects %d arguments, got %d", at, op, n, len(args))
+		}
+		return nil
+	}
+
+	switch op {
+	case "$$", "@_", "@~":
+		if err := arity(1); err != nil {
+			return "", err
+		}
+		s, err := atom(args[0])
+		if err != nil {
+			return "", err
+		}
+		return w.token(s, map[string]string{"@_": "glob", "@~": "phantom"}[op]), nil
+
+	case "$pred":
+		if err := arity(1); err != nil {
+			return "", err
+		}
+		name, err := atom(args[0])
+		if err != nil {
+			return "", err
+		}
+		f := w.next()
+		w.fn(f, fmt.Sprintf("if p.isRune(pos) {\nreturn p.apply(parser.P[\"$pred\"](p.preds[%[1]q]), pos)\n}\nif pos < len(p.toks) && p.preds[%[1]q](p.toks[pos]) {\nreturn p.toks[pos : pos+1], pos + 1, true\n}\nreturn nil, 0, false", name))
+		return f, nil
+
+	case "$kind":
+		if err := arity(1); err != nil {
+			return "", err
+		}
+		name, err := atom(args[0])
+		if err != nil {
+			return "", err
+		}
+		c := w.combinator(fmt.Sprintf("parser.Kind(%q)", name))
+		f := w.next()
+		w.fn(f, fmt.Sprintf("if p.isRune(pos) {\nreturn p.apply(%s, pos)\n}\nif pos < len(p.toks) && p.toks[pos].Type == %q {\nreturn p.toks[pos : pos+1], pos + 1, true\n}\nreturn nil, 0, false", c, name))
+		return f, nil
+
+	case "$str", "$regex", "$class":
+		if err := arity(1); err != nil {
+			return "", err
+		}
+		s, err := atom(args[0])
+		if err != nil {
+			return "", err
+		}
+		c := w.combinator(fmt.Sprintf("parser.S[%q](%q)", op, s))
+		f := w.next()
+		w.fn(f, fmt.Sprintf("return p.apply(%s, pos)", c))
+		return f, nil
+
+	case "$lexeme":
+		if len(args) == 0 {
+			return "", fmt.Errorf("%d: %s expects a type name", at, op)
+		}
+		tp, err := atom(args[0])
+		if err != nil {
+			return "", err
+		}
+		fs, err := w.exprs(args[1:])
+		if err != nil {
+			return "", err
+		}
+		f := w.next()
+		w.fn(f, "start := pos\n"+seq(fs)+fmt.Sprintf("tok := parser.Span(%q, p.toks[start:], pos-start)\n_, pos, _ = p.apply(parser.Spaces, pos)\nreturn []*parser.Node{tok}, pos, true", tp))
+		return f, nil
+
+	case "@seq", "@...", "$glob", "$phantom":
+		fs, err := w.exprs(args)
+		if err != nil {
+			return "", err
+		}
+		f := w.next()
+		ret := map[string]string{
+			"@seq":     "return ns, pos, true",
+			"@...":     "return dropPhantoms(ns), pos, true",
+			"$glob":    "return nil, pos, true",
+			"$phantom": "return phantom(dropPhantoms(ns)), pos, true",
+		}[op]
+		w.fn(f, seq(fs)+ret)
+		return f, nil
+
+	case "@!", "@?":
+		fs, err := w.exprs(args)
+		if err != nil {
+			return "", err
+		}
+		c := w.wrap(fs)
+		f := w.next()
+		if op == "@?" {
+			w.fn(f, fmt.Sprintf("if t, r, ok := p.%s(pos); ok {\nreturn t, r, true\n}\nreturn nil, pos, true", c))
+		} else {
+			w.fn(f, fmt.Sprintf("if pos >= len(p.toks) {\nreturn nil, 0, false\n}\nif _, _, ok := p.%s(pos); ok {\nreturn nil, 0, false\n}\nreturn p.toks[pos : pos+1], pos + 1, true", c))
+		}
+		return f, nil
+
+	case "@^":
+		fs, err := w.exprs(args)
+		if err != nil {
+			return "", err
+		}
+		var b strings.Builder
+		b.WriteString("var ns []*parser.Node\n")
+		for _, c := range fs {
+			fmt.Fprintf(&b, "if t, r, ok := p.%s(pos); !ok {\npanic(parser.Unexpected(p.toks[pos:]))\n} else {\nns, pos = append(ns, t...), r\n}\n", c)
+		}
+		b.WriteString("return ns, pos, true")
+		f := w.next()
+		w.fn(f, b.String())
+		return f, nil
+
+	case "@or":
+		fs, err := w.exprs(args)
+		if err != nil {
+			return "", err
+		}
+		f := w.next()
+		var b strings.Builder
+		for _, c := range fs {
+			fmt.Fprintf(&b, "if t, r, ok := p.%s(pos); ok {\nreturn t, r, true\n}\n", c)
+		}
+		b.WriteString("return nil, 0, false")
+		w.fn(f, b.String())
+		return f, nil
+
+	case "@and":
+		fs, err := w.exprs(args)
+		if err != nil {
+			return "", err
+		}
+		f := w.next()
+		var b strings.Builder
+		b.WriteString("var ns []*parser.Node\nr := pos\n")
+		for _, c := range fs {
+			fmt.Fprintf(&b, "if t, rr, ok := p.%s(pos); !ok {\nreturn nil, 0, false\n} else {\nns, r = t, rr\n}\n", c)
+		}
+		b.WriteString("return ns, r, true")
+		w.fn(f, b.String())
+		return f, nil
+
+	case "@*", "@*^", "@+":
+		fs, err := w.exprs(args)
+		if err != nil {
+			return "", err
+		}
+		if op == "@+" {
+			if err := arity(1); err != nil {
+				return "", err
+			}
+		}
+		item := w.wrap(fs)
+		if op == "@*^" {
+			if err := arity(1); err != nil {
+				return "", err
+			}
+			item = fs[0]
+		}
+		f := w.next()
+		loop := fmt.Sprintf("for pos < len(p.toks) {\nt, r, ok := p.%s(pos)\nif !ok {\nbreak\n}\nns, pos = append(ns, t...), r\n}\n", item)
+		if op == "@+" {
+			w.fn(f, fmt.Sprintf("ns, pos, ok := p.%s(pos)\nif !ok {\nreturn nil, 0, false\n}\n%sreturn ns, pos, true", item, loop))
+		} else {
+			w.fn(f, "var ns []*parser.Node\n"+loop+"return ns, pos, true")
+		}
+		return f, nil
+
+	case "@!^", "$glob^", "::":
+		if err := arity(1); err != nil {
+			return "", err
+		}
+		c, err := w.expr(args[0])
+		if err != nil {
+			return "", err
+		}
+		f := w.next()
+		switch op {
+		case "@!^":
+			w.fn(f, fmt.Sprintf("if pos >= len(p.toks) {\nreturn nil, 0, false\n}\nif _, _, ok := p.%s(pos); ok {\nreturn nil, 0, false\n}\nreturn p.toks[pos : pos+1], pos + 1, true", c))
+		case "$glob^":
+			w.fn(f, fmt.Sprintf("if _, r, ok := p.%s(pos); ok {\nreturn nil, r, true\n}\nreturn nil, 0, false", c))
+		case "::":
+			w.fn(f, fmt.Sprintf("return p.cached(%d, pos, (*Parser).%s)", w.memo, c))
+			w.memo++
+		}
+		return f, nil
+
+	case "@=":
+		if len(args) == 0 {
+			return "", fmt.Errorf("%d: %s expects a type name", at, op)
+		}
+		tp, err := atom(args[0])
+		if err != nil {
+			return "", err
+		}
+		fs, err := w.exprs(args[1:])
+		if err != nil {
+			return "", err
+		}
+		f := w.next()
+		if tp == "" {
+			w.fn(f, seq(fs)+"return dropPhantoms(ns), pos, true")
+		} else {
+			w.fn(f, "start := pos\n"+seq(fs)+fmt.Sprintf("if len(ns) == 0 {\ns := p.startOf(start)\nreturn []*parser.Node{{Type: %[1]q, Start: s, End: s}}, pos, true\n}\n"+
+				"return []*parser.Node{{Type: %[1]q, Start: ns[0].Start, End: ns[len(ns)-1].End, Elts: dropPhantoms(ns)}}, pos, true", tp))
+		}
+		return f, nil
+
+	case "@.@":
+		if err := arity(2); err != nil {
+			return "", err
+		}
+		fs, err := w.exprs(args)
+		if err != nil {
+			return "", err
+		}
+		f := w.next()
+		w.fn(f, fmt.Sprintf("ns, pos, ok := p.%[1]s(pos)\nif !ok {\nreturn nil, 0, false\n}\nfor pos < len(p.toks) {\nts, r, ok := p.%[2]s(pos)\nif !ok {\nbreak\n}\ntc, r, ok := p.%[1]s(r)\nif !ok {\nbreak\n}\nns, pos = append(append(ns, ts...), tc...), r\n}\nreturn dropPhantoms(ns), pos, true", fs[0], fs[1]))
+		return f, nil
+
+	case "@sep-by", "@sep-end-by":
+		if err := arity(2); err != nil {
+			return "", err
+		}
+		fs, err := w.exprs(args)
+		if err != nil {
+			return "", err
+		}
+		trailing := ""
+		if op == "@sep-end-by" {
+			trailing = fmt.Sprintf("if t, r, ok := p.%s(pos); ok {\nns, pos = append(ns, t...), r\n}\n", fs[1])
+		}
+		f := w.next()
+		w.fn(f, fmt.Sprintf("ns, r, ok := p.%[1]s(pos)\nif !ok {\nreturn nil, pos, true\n}\npos = r\nfor pos < len(p.toks) {\nts, r, ok := p.%[2]s(pos)\nif !ok {\nbreak\n}\ntc, r, ok := p.%[1]s(r)\nif !ok || r == pos {\nbreak\n}\nns, pos = append(append(ns, ts...), tc...), r\n}\n%[3]sreturn dropPhantoms(ns), pos, true", fs[0], fs[1], trailing))
+		return f, nil
+
+	case "@many-till":
+		if err := arity(2); err != nil {
+			return "", err
+		}
+		fs, err := w.exprs(args)
+		if err != nil {
+			return "", err
+		}
+		f := w.next()
+		w.fn(f, fmt.Sprintf("var ns []*parser.Node\nfor {\nif _, r, ok := p.%[2]s(pos); ok {\nreturn ns, r, true\n}\nt, r, ok := p.%[1]s(pos)\nif !ok || r == pos {\nreturn nil, 0, false\n}\nns, pos = append(ns, t...), r\n}", fs[0], fs[1]))
+		return f, nil
+
+	case "@look-ahead", "@not-followed-by":
+		if err := arity(1); err != nil {
+			return "", err
+		}
+		c, err := w.expr(args[0])
+		if err != nil {
+			return "", err
+		}
+		cond := map[string]string{"@look-ahead": "!ok", "@not-followed-by": "ok"}[op]
+		f := w.next()
+		w.fn(f, fmt.Sprintf("if _, _, ok := p.%s(pos); %s {\nreturn nil, 0, false\n}\nreturn nil, pos, true", c, cond))
+		return f, nil
+
+	case "@recover-until":
+		if err := arity(2); err != nil {
+			return "", err
+		}
+		fs, err := w.exprs(args)
+		if err != nil {
+			return "", err
+		}
+		f := w.next()
+		w.fn(f, fmt.Sprintf("t, r, ok, err := p.try((*Parser).%[1]s, pos)\nif ok {\nreturn t, r, true\n}\nn := 0\nfor pos+n < len(p.toks) {\nif _, _, ok := p.%[2]s(pos + n); ok {\nbreak\n}\nn++\n}\n"+
+			"if n == 0 && err != nil {\npanic(err)\n} else if n == 0 {\nreturn nil, 0, false\n}\nreturn []*parser.Node{parser.SkippedNode(p.toks[pos:], n, err)}, pos + n, true", fs[0], fs[1]))
+		return f, nil
+
+	case "@prefix", "@postfix", "@infix-left", "@infix-right", "@infix-none":
+		if err := arity(3); err != nil {
+			return "", err
+		}
+		tp, err := atom(args[0])
+		if err != nil {
+			return "", err
+		}
+		fs, err := w.exprs(args[1:])
+		if err != nil {
+			return "", err
+		}
+		c, o := fs[0], fs[1]
+		f := w.next()
+		switch op {
+		case "@prefix":
+			w.fn(f, fmt.Sprintf("var ns []*parser.Node\nfor pos < len(p.toks) {\nt, r, ok := p.%[2]s(pos)\nif !ok {\nbreak\n}\nns, pos = append(ns, t...), r\n}\nif len(ns) == 0 {\nreturn nil, 0, false\n}\n"+
+				"t, r, ok := p.%[1]s(pos)\nif !ok {\nreturn nil, 0, false\n}\nreturn []*parser.Node{parser.MakePrefix(%[3]q, dropPhantoms(append(ns, t...)))}, r, true", c, o, tp))
+		case "@postfix":
+			w.fn(f, fmt.Sprintf("ns, pos, ok := p.%[1]s(pos)\nif !ok {\nreturn nil, 0, false\n}\nn := len(ns)\nfor pos < len(p.toks) {\nt, r, ok := p.%[2]s(pos)\nif !ok {\nbreak\n}\nns, pos = append(ns, t...), r\n}\nif len(ns) == n {\nreturn nil, 0, false\n}\n"+
+				"return []*parser.Node{parser.MakePostfix(%[3]q, dropPhantoms(ns))}, pos, true", c, o, tp))
+		default:
+			assoc := strings.TrimPrefix(op, "@infix-")
+			check := ""
+			if assoc == "none" {
+				check = "if len(ns) > 3 {\npanic(parser.NonAssociative(ns[3]))\n}\n"
+			}
+			w.fn(f, fmt.Sprintf("ns, pos, ok := p.%[1]s(pos)\nif !ok {\nreturn nil, 0, false\n}\nn := len(ns)\nfor {\nto, r, ok := p.%[2]s(pos)\nif !ok {\nbreak\n}\ntc, r, ok := p.%[1]s(r)\nif !ok {\nbreak\n}\nns, pos = append(append(ns, to...), tc...), r\n}\nif len(ns) == n {\nreturn nil, 0, false\n}\n"+
+				"%[5]sreturn []*parser.Node{parser.MakeInfix(%[3]q, ns, %[4]q)}, pos, true", c, o, tp, assoc, check))
+		}
+		return f, nil
+	}
+	return "", fmt.Errorf("%d: unsupported operator %q", at, op)
+}
+
+// 相当于 (@... cs), 用于 @* 等对 cs 隐式使用 @... 的地方
+func (w *writer) wrap(fs []string) string {
+	f := w.next()
+	w.fn(f, seq(fs)+"return dropPhantoms(ns), pos, true")
+	return f
+}
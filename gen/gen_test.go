@@ -0,0 +1,77 @@
+package gen
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Aiyane/parsec-go/parser"
+)
+
+// example/parser.go 要与当前的生成结果一致, 修改生成器后需要 go generate ./gen/example
+func TestExampleUpToDate(t *testing.T) {
+	src, err := os.ReadFile("example/example.peg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := parser.LoadGrammar(string(src), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := Generate(g, "example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := os.ReadFile("example/parser.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, old) {
+		t.Error("example/parser.go is out of date, run go generate ./gen/example")
+	}
+}
+
+func TestGenerateDeclared(t *testing.T) {
+	g, err := parser.DeclareGrammar(nil,
+		parser.RuleDef{Name: "list", Body: parser.Call("@.@", parser.Sym("item"), parser.Call("@_", parser.Lit(",")))},
+		parser.RuleDef{Name: "item", Body: parser.Call("$pred", parser.Sym("id"))},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := Generate(g, "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package list", `"list": (*Parser).rule0_list`, `"item": (*Parser).rule1_item`, `p.preds["id"]`} {
+		if !bytes.Contains(code, []byte(want)) {
+			t.Errorf("generated code does not contain %q", want)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	g := parser.NewGrammar()
+	g.Define("a", parser.C["$none"])
+	if _, err := Generate(g, "p"); err == nil || !strings.Contains(err.Error(), `rule "a" has no grammar definition`) {
+		t.Errorf("Define: error = %v", err)
+	}
+
+	// Defs 中的规则体不经过 DeclareGrammar 的检查
+	g = parser.NewGrammar()
+	g.Names = []string{"a", "b"}
+	g.Defs["a"] = parser.Call("@nope", parser.Lit("x"))
+	g.Defs["b"] = parser.Sym("a")
+	if _, err := Generate(g, "p"); err == nil || !strings.Contains(err.Error(), `unsupported operator "@nope"`) {
+		t.Errorf("unknown operator: error = %v", err)
+	}
+	g.Defs["a"] = parser.Call("@+", parser.Lit("x"), parser.Lit("y"))
+	if _, err := Generate(g, "p"); err == nil || !strings.Contains(err.Error(), "@+ expects 1 arguments, got 2") {
+		t.Errorf("arity: error = %v", err)
+	}
+	g.Defs["a"] = parser.Sym("c")
+	if _, err := Generate(g, "p"); err == nil || !strings.Contains(err.Error(), `undefined rule "c"`) {
+		t.Errorf("undefined rule: error = %v", err)
+	}
+}
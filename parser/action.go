@@ -0,0 +1,87 @@
+package parser
+
+// 语义动作
+// --------------------------------------------
+//
+// 解析时直接对匹配到的节点运行 Go 函数, 不必在解析后另外遍历语法树.
+// Value 产生 Type 为 value 的节点, 计算结果放在 Value 中, 匹配到的节点放在 Elts 中.
+//
+//	num := Value(P["$pred"](IsNumber), func(ns []*Node) interface{} { return ns[0].Value })
+//	sum := Bind(num, func(ns []*Node) Combinator {
+//		return Fold(B["@seq"](S["@_"]("+"), num), ns[0].Value, func(acc interface{}, ns []*Node) interface{} {
+//			return acc.(int64) + ns[0].Value.(int64)
+//		})
+//	})
+//
+// Eval(sum, Scan("1 + 2 + 3")) 得到一个 Value 为 int64(6) 的节点.
+
+// 用 f 变换 c 的结果, f 返回 nil 时视为失败
+func Map(c Combinator, f func([]*Node) []*Node) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			t, r := ApplyCheck(c, toks, stk, ctx)
+			if t == nil {
+				return nil, nil
+			}
+			if t = f(t); t == nil {
+				return nil, nil
+			}
+			return t, r
+		}
+	}
+}
+
+func valueNode(toks, nodes []*Node, value interface{}) *Node {
+	start, end := position(toks), position(toks)
+	if len(nodes) > 0 {
+		start, end = nodes[0].Start, nodes[len(nodes)-1].End
+	}
+	return &Node{Type: ValueType, Start: start, End: end, Elts: nodes, Value: value}
+}
+
+// c 匹配后, 用 f 计算一个值
+func Value(c Combinator, f func([]*Node) interface{}) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			t, r := ApplyCheck(c, toks, stk, ctx)
+			if t == nil {
+				return nil, nil
+			}
+			return []*Node{valueNode(toks, t, f(t))}, r
+		}
+	}
+}
+
+// c 匹配后, 根据其结果选择接下来的解析器, 结果为后者的结果
+// FlatMap 同 Bind
+func Bind(c Combinator, f func([]*Node) Combinator) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			t, r := ApplyCheck(c, toks, stk, ctx)
+			if t == nil {
+				return nil, nil
+			}
+			return ApplyCheck(f(t), r, stk, ctx)
+		}
+	}
+}
+
+var FlatMap = Bind
+
+// 同 @*, 每次 c 匹配后用 f 累积, 结果为一个 Value 为累积值的节点
+func Fold(c Combinator, init interface{}, f func(acc interface{}, nodes []*Node) interface{}) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			acc, nodes, rest := init, make([]*Node, 0), toks
+			for len(rest) > 0 {
+				t, r := ApplyCheck(c, rest, stk, ctx)
+				// 不消耗输入时停止, 避免死循环
+				if t == nil || len(r) == len(rest) {
+					break
+				}
+				acc, nodes, rest = f(acc, t), append(nodes, t...), r
+			}
+			return []*Node{valueNode(toks, nodes, acc)}, rest
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package parser
+
+import "testing"
+
+var num = Value(P["$pred"](IsNumber), func(ns []*Node) interface{} { return ns[0].Value })
+
+// 文档中的例子
+func TestFoldSum(t *testing.T) {
+	SetParameters()
+	sum := Bind(num, func(ns []*Node) Combinator {
+		return Fold(B["@seq"](S["@_"]("+"), num), ns[0].Value, func(acc interface{}, ns []*Node) interface{} {
+			return acc.(int64) + ns[0].Value.(int64)
+		})
+	})
+	got, _ := Eval(sum, Scan("1 + 2 + 3"))
+	if len(got) != 1 || got[0].Type != ValueType || got[0].Value != int64(6) {
+		t.Fatalf("got %s", dumps(got))
+	}
+	if got[0].Start != 4 || got[0].End != 9 || dumps(got[0].Elts) != "(value number:2) (value number:3)" {
+		t.Errorf("got %d-%d %s", got[0].Start, got[0].End, dumps(got[0].Elts))
+	}
+}
+
+func TestMap(t *testing.T) {
+	SetParameters()
+	// 交换两个操作数
+	swap := Map(B["@seq"](num, S["$$"]("-"), num), func(ns []*Node) []*Node {
+		return []*Node{ns[2], ns[1], ns[0]}
+	})
+	if got, _ := Eval(swap, Scan("1 - 2")); dumps(got) != "(value number:2) token:- (value number:1)" {
+		t.Errorf("got %s", dumps(got))
+	}
+	// f 返回 nil 时失败, 继续尝试 @or 的下一个分支
+	even := Map(num, func(ns []*Node) []*Node {
+		if ns[0].Value.(int64)%2 != 0 {
+			return nil
+		}
+		return ns
+	})
+	c := B["@or"](even, P["$pred"](IsNumber))
+	if got, _ := Eval(c, Scan("3")); dumps(got) != "number:3" {
+		t.Errorf("got %s", dumps(got))
+	}
+	if got, _ := Eval(c, Scan("4")); dumps(got) != "(value number:4)" {
+		t.Errorf("got %s", dumps(got))
+	}
+}
+
+func TestValueEmpty(t *testing.T) {
+	SetParameters()
+	// 匹配为空时, 位置取当前 token 的开始
+	c := B["@seq"](S["@_"]("a"), Value(C["$none"], func([]*Node) interface{} { return "none" }))
+	got, _ := Eval(c, Scan("a b"))
+	if len(got) != 1 || got[0].Value != "none" || got[0].Start != 2 || got[0].End != 2 {
+		t.Errorf("got %s", dumps(got))
+	}
+}
+
+func TestBind(t *testing.T) {
+	SetParameters()
+	// 先读数量 n, 再读 n 个 id
+	c := FlatMap(num, func(ns []*Node) Combinator {
+		return Count(int(ns[0].Value.(int64)), P["$pred"](Preds["id"]))
+	})
+	if got, _ := Eval(c, Scan("2 a b c")); dumps(got) != "token:a token:b" {
+		t.Errorf("got %s", dumps(got))
+	}
+	if got, _ := Eval(c, Scan("3 a b")); got != nil {
+		t.Errorf("got %s, want failure", dumps(got))
+	}
+}
+
+func TestFoldNoProgress(t *testing.T) {
+	SetParameters()
+	// c 可以不消耗输入, Fold 不会死循环
+	n := 0
+	c := Fold(B["@?"](S["@_"]("a")), 0, func(acc interface{}, ns []*Node) interface{} {
+		n++
+		return acc.(int) + 1
+	})
+	got, _ := Eval(c, Scan("a a b"))
+	if len(got) != 1 || got[0].Value != 2 || n != 2 {
+		t.Errorf("got %s value %v, %d calls", dumps(got), got[0].Value, n)
+	}
+}
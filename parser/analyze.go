@@ -0,0 +1,418 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 文法静态检查
+// --------------------------------------------
+//
+// 在 Grammar 的规则体 (Defs) 上做分析, 报告:
+//
+//	left-recursion   规则在不消耗 token 的情况下调用回自身, 解析时会栈溢出
+//	nullable-loop    @* @+ 等循环的循环体可以不消耗 token 而成功, 解析时会死循环
+//	shadowed         @or 中的分支永远不会被选中 (前面的分支总会先成功)
+//	prefix           @or 中前面的 $str 是后面 $str 的文本前缀, 逐字符匹配时后者无法匹配
+//	unreachable      从起始规则无法到达的规则
+const (
+	LeftRecursion = "left-recursion"
+	NullableLoop  = "nullable-loop"
+	Shadowed      = "shadowed"
+	PrefixShadow  = "prefix"
+	Unreachable   = "unreachable"
+)
+
+type Issue struct {
+	Kind string
+	Rule string
+	Pos  int
+	Msg  string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%d: %s: rule %q: %s", i.Pos, i.Kind, i.Rule, i.Msg)
+}
+
+// 运算符的子表达式, 跳过类型名, 字面量等参数
+func Operands(n *Node) []*Node {
+	if n.Type != "sexp" {
+		return nil
+	}
+	elts := filter(negate(IsComment), n.Elts)
+	if len(elts) == 0 || !IsTokenType(elts[0]) {
+		return nil
+	}
+	op, args := elts[0].Text, elts[1:]
+	if _, ok := S[op]; ok {
+		return nil
+	}
+	if _, ok := P[op]; ok {
+		return nil
+	}
+	_, isT := T[op]
+	_, isF := F[op]
+	if (isT || isF) && len(args) > 0 {
+		return args[1:]
+	}
+	return args
+}
+
+// 运算符名, 原子返回空串
+func operator(n *Node) string {
+	if n.Type != "sexp" {
+		return ""
+	}
+	elts := filter(negate(IsComment), n.Elts)
+	if len(elts) == 0 || !IsTokenType(elts[0]) {
+		return ""
+	}
+	return elts[0].Text
+}
+
+// 规则 name 直接引用的规则
+func (g *Grammar) Refs(name string) []string {
+	seen := make(map[string]bool)
+	refs := make([]string, 0)
+	var walk func(n *Node)
+	walk = func(n *Node) {
+		if IsTokenType(n) {
+			if _, ok := g.Defs[n.Text]; ok && !seen[n.Text] {
+				seen[n.Text] = true
+				refs = append(refs, n.Text)
+			}
+			return
+		}
+		for _, e := range Operands(n) {
+			walk(e)
+		}
+	}
+	if def, ok := g.Defs[name]; ok {
+		walk(def)
+	}
+	return refs
+}
+
+// 规则 name 能否不消耗 token 而成功
+func (g *Grammar) Nullable(name string) bool {
+	return g.nullables()[name]
+}
+
+func (g *Grammar) nullables() map[string]bool {
+	nullable := make(map[string]bool, len(g.Names))
+	for changed := true; changed; {
+		changed = false
+		for _, name := range g.Names {
+			if def, ok := g.Defs[name]; ok && !nullable[name] && g.nullable(def, nullable) {
+				nullable[name] = true
+				changed = true
+			}
+		}
+	}
+	return nullable
+}
+
+func (g *Grammar) nullable(n *Node, rules map[string]bool) bool {
+	all := func(ns []*Node) bool {
+		for _, e := range ns {
+			if !g.nullable(e, rules) {
+				return false
+			}
+		}
+		return true
+	}
+	switch {
+	case IsStrType(n):
+		return false
+	case IsTokenType(n):
+		return n.Text == "$none" || n.Text == "$spaces" || rules[n.Text]
+	}
+	args := Operands(n)
+	switch operator(n) {
+	case "@or":
+		for _, e := range args {
+			if g.nullable(e, rules) {
+				return true
+			}
+		}
+		return false
+	case "@*", "@*^", "@?", "@sep-by", "@sep-end-by", "@look-ahead", "@not-followed-by":
+		return true
+	case "@many-till":
+		return len(args) == 2 && g.nullable(args[1], rules)
+	case "@!", "@!^", "$$", "@_", "@~", "$pred", "$kind", "$str", "$class":
+		return false
+	case "$regex":
+		// 正则能匹配空串时可空, 正则是字面量参数, 不在 Operands 中
+		elts := filter(negate(IsComment), n.Elts)
+		if len(elts) != 2 {
+			return false
+		}
+		src, ok := atomText(elts[1])
+		if !ok {
+			return false
+		}
+		re, err := regexp.Compile(`^(?:` + src + `)`)
+		return err == nil && re.MatchString("")
+	case "$lexeme":
+		// Operands 已跳过类型名
+		return all(args)
+	case "@and":
+		return len(args) > 0 && g.nullable(args[len(args)-1], rules)
+	case "@.@":
+		return len(args) > 0 && g.nullable(args[0], rules)
+	}
+	return all(args)
+}
+
+// 在当前位置 (还未消耗 token 时) 可能调用的规则
+func (g *Grammar) leftCalls(n *Node, nullable map[string]bool) []string {
+	if IsTokenType(n) {
+		if _, ok := g.Defs[n.Text]; ok {
+			return []string{n.Text}
+		}
+		return nil
+	}
+	args := Operands(n)
+	calls := make([]string, 0)
+	switch operator(n) {
+	case "@or", "@and", "@!", "@!^", "@many-till":
+		for _, e := range args {
+			calls = append(calls, g.leftCalls(e, nullable)...)
+		}
+		return calls
+	case "@prefix":
+		args = reverse(args)
+	}
+	for _, e := range args {
+		calls = append(calls, g.leftCalls(e, nullable)...)
+		if !g.nullable(e, nullable) {
+			break
+		}
+	}
+	return calls
+}
+
+// 可能无限循环的 @* @+ 等
+func (g *Grammar) nullableLoops(n *Node, nullable map[string]bool, report func(*Node, string)) {
+	args := Operands(n)
+	switch op := operator(n); op {
+	case "@*", "@*^", "@+":
+		if g.nullable(&Node{Type: "sexp", Elts: append([]*Node{{Type: TokenType, Text: "@seq"}}, args...)}, nullable) {
+			report(n, fmt.Sprintf("body of %s can succeed without consuming input", op))
+		}
+	case "@.@", "@sep-by", "@sep-end-by":
+		if len(args) == 2 && g.nullable(args[0], nullable) && g.nullable(args[1], nullable) {
+			report(n, fmt.Sprintf("both element and separator of %s can succeed without consuming input", op))
+		}
+	case "@prefix", "@postfix":
+		if len(args) == 2 && g.nullable(args[1], nullable) {
+			report(n, fmt.Sprintf("operator of %s can succeed without consuming input", op))
+		}
+	}
+	for _, e := range args {
+		g.nullableLoops(e, nullable, report)
+	}
+}
+
+// 分支开头的字面量 token 序列, exact 表示分支恰好匹配这些 token
+func (g *Grammar) literals(n *Node, seen map[string]bool) (lits []string, exact bool) {
+	switch {
+	case IsStrType(n):
+		return []string{n.Text}, true
+	case IsTokenType(n):
+		def, ok := g.Defs[n.Text]
+		if !ok || seen[n.Text] {
+			return nil, false
+		}
+		seen[n.Text] = true
+		defer delete(seen, n.Text)
+		return g.literals(def, seen)
+	}
+	elts := filter(negate(IsComment), n.Elts)
+	switch operator(n) {
+	case "$$", "@_", "@~":
+		if len(elts) == 2 {
+			if s, ok := atomText(elts[1]); ok {
+				return []string{s}, true
+			}
+		}
+	case "::":
+		if args := Operands(n); len(args) == 1 {
+			return g.literals(args[0], seen)
+		}
+	case "@seq", "@...", "@=", "$glob", "$phantom":
+		for _, e := range Operands(n) {
+			l, ok := g.literals(e, seen)
+			lits = append(lits, l...)
+			if !ok {
+				return lits, false
+			}
+		}
+		return lits, true
+	}
+	return nil, false
+}
+
+// 逐字符匹配的字面量 ($str "...") 的文本. 按 token 匹配的字面量不会匹配到更长 token 的一部分, 不在此列
+func (g *Grammar) charLiteral(n *Node, seen map[string]bool) (string, bool) {
+	if IsTokenType(n) {
+		def, ok := g.Defs[n.Text]
+		if !ok || seen[n.Text] {
+			return "", false
+		}
+		seen[n.Text] = true
+		defer delete(seen, n.Text)
+		return g.charLiteral(def, seen)
+	}
+	elts := filter(negate(IsComment), n.Elts)
+	switch operator(n) {
+	case "$str":
+		if len(elts) == 2 {
+			return atomText(elts[1])
+		}
+	case "::", "$glob":
+		if args := Operands(n); len(args) == 1 {
+			return g.charLiteral(args[0], seen)
+		}
+	}
+	return "", false
+}
+
+func hasPrefix(s, prefix []string) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Grammar) shadowed(n *Node, nullable map[string]bool, report func(*Node, string, string)) {
+	args := Operands(n)
+	if operator(n) == "@or" {
+		type lit struct {
+			toks  []string
+			exact bool
+		}
+		lits := make([]lit, len(args))
+		chars := make([]string, len(args))
+		for i, e := range args {
+			lits[i].toks, lits[i].exact = g.literals(e, make(map[string]bool))
+			chars[i], _ = g.charLiteral(e, make(map[string]bool))
+		}
+		for j := 1; j < len(args); j++ {
+			for i := 0; i < j; i++ {
+				if g.nullable(args[i], nullable) {
+					report(args[j], Shadowed, fmt.Sprintf("alternative %d is never tried: alternative %d can match empty input", j+1, i+1))
+					break
+				}
+				if lits[i].exact && len(lits[i].toks) > 0 && hasPrefix(lits[j].toks, lits[i].toks) {
+					report(args[j], Shadowed, fmt.Sprintf("alternative %d is never chosen: alternative %d matches its prefix %q", j+1, i+1, strings.Join(lits[i].toks, " ")))
+					break
+				}
+				if chars[i] != "" && chars[i] != chars[j] && strings.HasPrefix(chars[j], chars[i]) {
+					report(args[j], PrefixShadow, fmt.Sprintf("%q is tried after its prefix %q", chars[j], chars[i]))
+					break
+				}
+			}
+		}
+	}
+	for _, e := range args {
+		g.shadowed(e, nullable, report)
+	}
+}
+
+// 检查文法, start 为空时以第一条规则为起始规则
+func (g *Grammar) Analyze(start string) []Issue {
+	if start == "" && len(g.Names) > 0 {
+		start = g.Names[0]
+	}
+	nullable := g.nullables()
+	issues := make([]Issue, 0)
+
+	// Define 定义的规则没有规则体, 不做分析, 并保守地视为可达
+	names := make([]string, 0, len(g.Names))
+	reachable := map[string]bool{start: true}
+	queue := []string{start}
+	for _, name := range g.Names {
+		if _, ok := g.Defs[name]; ok {
+			names = append(names, name)
+		} else if !reachable[name] {
+			reachable[name] = true
+			queue = append(queue, name)
+		}
+	}
+
+	// 左递归: 在左调用图上找环, 每个环只报告一次
+	calls := make(map[string][]string, len(g.Names))
+	for _, name := range names {
+		calls[name] = g.leftCalls(g.Defs[name], nullable)
+	}
+	reported := make(map[string]bool)
+	for _, name := range names {
+		visited := make(map[string]bool)
+		var find func(path []string) []string
+		find = func(path []string) []string {
+			for _, next := range calls[path[len(path)-1]] {
+				if next == name {
+					return append(path, next)
+				}
+				if !visited[next] {
+					visited[next] = true
+					if cycle := find(append(path, next)); cycle != nil {
+						return cycle
+					}
+				}
+			}
+			return nil
+		}
+		cycle := find([]string{name})
+		if cycle == nil || reported[name] {
+			continue
+		}
+		for _, r := range cycle {
+			reported[r] = true
+		}
+		issues = append(issues, Issue{
+			Kind: LeftRecursion,
+			Rule: name,
+			Pos:  g.Defs[name].Start,
+			Msg:  strings.Join(cycle, " -> "),
+		})
+	}
+
+	for _, name := range names {
+		g.nullableLoops(g.Defs[name], nullable, func(n *Node, msg string) {
+			issues = append(issues, Issue{Kind: NullableLoop, Rule: name, Pos: n.Start, Msg: msg})
+		})
+		g.shadowed(g.Defs[name], nullable, func(n *Node, kind, msg string) {
+			issues = append(issues, Issue{Kind: kind, Rule: name, Pos: n.Start, Msg: msg})
+		})
+	}
+
+	for len(queue) > 0 {
+		for _, r := range g.Refs(queue[0]) {
+			if !reachable[r] {
+				reachable[r] = true
+				queue = append(queue, r)
+			}
+		}
+		queue = queue[1:]
+	}
+	for _, name := range names {
+		if !reachable[name] {
+			issues = append(issues, Issue{
+				Kind: Unreachable,
+				Rule: name,
+				Pos:  g.Defs[name].Start,
+				Msg:  fmt.Sprintf("not reachable from %q", start),
+			})
+		}
+	}
+	return issues
+}
@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func analyze(t *testing.T, src string) []string {
+	t.Helper()
+	g, err := LoadGrammar(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := make([]string, 0)
+	for _, i := range g.Analyze("") {
+		issues = append(issues, i.Kind+" "+i.Rule+": "+i.Msg)
+	}
+	return issues
+}
+
+func TestAnalyzeLeftRecursionAndUnreachable(t *testing.T) {
+	got := analyze(t, `
+expr   <- (@or (@and expr "+" "x") "x")
+unused <- "y"
+`)
+	want := []string{
+		`left-recursion expr: expr -> expr`,
+		`unreachable unused: not reachable from "expr"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAnalyzeNullableLoops(t *testing.T) {
+	got := analyze(t, `
+top  <- (@seq (@* (@? "a")) (@.@ (@? "b") (@? ",")) list)
+list <- (@+ $none)
+`)
+	want := []string{
+		`nullable-loop top: body of @* can succeed without consuming input`,
+		`nullable-loop top: both element and separator of @.@ can succeed without consuming input`,
+		`nullable-loop list: body of @+ can succeed without consuming input`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// 正则和 $lexeme 按参数判断是否可空
+func TestAnalyzeNullableChars(t *testing.T) {
+	got := analyze(t, `
+top <- (@seq a b c)
+a   <- (@* ($regex "a*"))
+b   <- (@* ($lexeme x ($str "b")))
+c   <- (@* ($lexeme y ($regex "c?")))
+`)
+	want := []string{
+		`nullable-loop a: body of @* can succeed without consuming input`,
+		`nullable-loop c: body of @* can succeed without consuming input`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAnalyzeShadowed(t *testing.T) {
+	got := analyze(t, `
+top <- (@seq rel op)
+rel <- (@or ($str "<") ($str "<=") (@seq "a" "b") (@seq "a" "b" "c") (@? "x") "y")
+op  <- (@or (@~ "a") (@~ "ab") "<" "<=")
+`)
+	want := []string{
+		`prefix rel: "<=" is tried after its prefix "<"`,
+		`shadowed rel: alternative 4 is never chosen: alternative 3 matches its prefix "a b"`,
+		`shadowed rel: alternative 6 is never tried: alternative 5 can match empty input`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAnalyzeClean(t *testing.T) {
+	if got := analyze(t, testGrammar); len(got) != 0 {
+		t.Errorf("unexpected issues:\n%s", strings.Join(got, "\n"))
+	}
+}
+
+func TestNullableAndRefs(t *testing.T) {
+	g, err := LoadGrammar(`
+a <- (@seq b (@? c))
+b <- (@* "x")
+c <- "y"
+`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"a": true, "b": true, "c": false} {
+		if got := g.Nullable(name); got != want {
+			t.Errorf("Nullable(%q) = %v, want %v", name, got, want)
+		}
+	}
+	if got := strings.Join(g.Refs("a"), " "); got != "b c" {
+		t.Errorf("Refs(a) = %q, want \"b c\"", got)
+	}
+}
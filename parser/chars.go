@@ -0,0 +1,216 @@
+package parser
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 无扫描器 (字符级) 解析
+// --------------------------------------------
+//
+// ScanChars 把输入按字符切开, 每个字符一个 rune 节点, 组合子直接在字符上工作,
+// 用于词法依赖上下文的语言, 如某些 SQL 方言中 `-` 既是运算符又可以出现在标识符中.
+//
+//	ident := Lexeme("id", Regex(`[a-z]+(-[a-z]+)*`))
+//	expr  := B["@seq"](ident, S["$$"]("-"), ident)
+//	Eval(B["@seq"](Spaces, expr), ScanChars("a-b - c"))
+//
+// Char, CharClass 匹配单个字符; Str, Regex 匹配一段字符, 合并为一个 token 节点.
+// Lexeme 把 c 匹配的字符合并为一个类型为 tp 的节点, 并跳过其后的空白, 相当于在文法中定义 token.
+// 基于 token 的规则可以直接用在字符输入上, 因此两者可以混用:
+// $$ @_ @~ 按 Lexeme(TokenType, Str(s)) 匹配; $pred $kind 从当前字符起按 Scan 的规则扫描一个 token,
+// 谓词为真时把它作为一个节点, 消耗其中的字符和之后的空白.
+//
+//	call := B["@seq"](P["$pred"](Preds["id"]), S["$$"]("("), Lexeme("arg", Regex(`[^)]*`)), S["$$"](")"))
+
+// 字符节点共用的输入
+type runeSource struct {
+	s string
+}
+
+// ScanChars 产生的节点, Ctx 为共用的输入
+func ScanChars(s string) []*Node {
+	src := &runeSource{s}
+	nodes := make([]*Node, 0, len(s))
+	for pos := 0; pos < len(s); {
+		// 非法的 UTF-8 字节各自成为一个字符
+		_, size := utf8.DecodeRuneInString(s[pos:])
+		nodes = append(nodes, NewNode(RuneType, pos, pos+size, nil, s[pos:pos+size], 0, src))
+		pos += size
+	}
+	return nodes
+}
+
+func IsRune(n *Node) bool {
+	return RuneType == n.Type
+}
+
+func source(n *Node) string {
+	if src, ok := n.Ctx.(*runeSource); ok {
+		return src.s
+	}
+	return ""
+}
+
+// 合并 toks 中前 n 个字符, 输入结束处的空匹配位置为 -1. 生成的解析器 (parsec-gen) 也用它实现 $lexeme
+func Span(tp string, toks []*Node, n int) *Node {
+	if len(toks) == 0 {
+		return NewNode(tp, -1, -1, nil, "", 0, nil)
+	}
+	start, end := toks[0].Start, toks[0].Start
+	if n > 0 {
+		end = toks[n-1].End
+	}
+	return NewNode(tp, start, end, nil, source(toks[0])[start:end], 0, nil)
+}
+
+// 跳过 toks 开头的空白字符
+func skipSpaces(toks []*Node) []*Node {
+	for len(toks) > 0 && IsRune(toks[0]) {
+		if r, ok := singleRune(toks[0].Text); !ok || !unicode.IsSpace(r) {
+			break
+		}
+		toks = toks[1:]
+	}
+	return toks
+}
+
+// 从字符 toks[0] 起按 Scan 的规则扫描一个 token, 返回 token 和其后 (跳过空白) 的字符.
+// toks[0] 是空白, 注释或扫描出错时返回 nil
+func scanRunes(toks []*Node) (tok *Node, r []*Node) {
+	defer func() {
+		if x := recover(); x != nil {
+			if _, ok := x.(*ScanError); !ok {
+				panic(x)
+			}
+			tok, r = nil, nil
+		}
+	}()
+	start := toks[0].Start
+	tok, end := scanToken(source(toks[0]), start)
+	if tok.Start != start || tok.Type == EofType || IsComment(tok) {
+		return nil, nil
+	}
+	n := 0
+	for n < len(toks) && toks[n].Start < end {
+		n++
+	}
+	return tok, skipSpaces(toks[n:])
+}
+
+// token 上的谓词, 在字符输入上先用 scanRunes 得到 token
+func tokenPred(proc func(*Node) bool) Combinator {
+	pred := _pred(proc)
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			if len(toks) == 0 || !IsRune(toks[0]) {
+				return pred()(toks, stk, ctx)
+			}
+			if tok, r := scanRunes(toks); tok != nil && proc(tok) {
+				return []*Node{tok}, r
+			}
+			return nil, nil
+		}
+	}
+}
+
+// 匹配 f 为真的字符
+func CharPred(f func(rune) bool) Combinator {
+	return _pred(func(x *Node) bool {
+		r, ok := singleRune(x.Text)
+		return IsRune(x) && ok && f(r)
+	})
+}
+
+func Char(c rune) Combinator {
+	return CharPred(func(r rune) bool { return r == c })
+}
+
+// class 为正则中方括号内的写法, 如 `a-z_`, `^0-9`
+// $class
+func CharClass(class string) Combinator {
+	re := regexp.MustCompile(`^[` + class + `]$`)
+	return CharPred(func(r rune) bool { return re.MatchString(string(r)) })
+}
+
+// $str
+func Str(s string) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			n := 0
+			for _, r := range s {
+				if n >= len(toks) || !IsRune(toks[n]) || toks[n].Text != string(r) {
+					return nil, nil
+				}
+				n++
+			}
+			return []*Node{Span(TokenType, toks, n)}, toks[n:]
+		}
+	}
+}
+
+// 从当前字符开始匹配正则
+// $regex
+func Regex(pattern string) Combinator {
+	re := regexp.MustCompile(`^(?:` + pattern + `)`)
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			if len(toks) == 0 {
+				if re.MatchString("") {
+					return []*Node{Span(TokenType, toks, 0)}, toks
+				}
+				return nil, nil
+			}
+			if !IsRune(toks[0]) {
+				return nil, nil
+			}
+			start := toks[0].Start
+			loc := re.FindStringIndex(source(toks[0])[start:])
+			if loc == nil {
+				return nil, nil
+			}
+			n := 0
+			for n < len(toks) && toks[n].Start < start+loc[1] {
+				n++
+			}
+			return []*Node{Span(TokenType, toks, n)}, toks[n:]
+		}
+	}
+}
+
+// 跳过空白字符
+// $spaces
+var Spaces = _glob(AtStar(CharPred(unicode.IsSpace)))
+
+// $lexeme
+func Lexeme(tp string, c Combinator) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			t, r := ApplyCheck(c, toks, stk, ctx)
+			if t == nil {
+				return nil, nil
+			}
+			tok := Span(tp, toks, len(toks)-len(r))
+			_, r = ApplyCheck(Spaces, r, stk, ctx)
+			return []*Node{tok}, r
+		}
+	}
+}
+
+// 字符输入上的字面量, s 以标识符字符结尾时要求后面不再是标识符字符, 如 SELECT 不匹配 SELECTED
+func charLiteral(s string) Combinator {
+	lex := Lexeme(TokenType, Str(s))
+	last, _ := utf8.DecodeLastRuneInString(s)
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			n := utf8.RuneCountInString(s)
+			if id_part(last) && n < len(toks) {
+				if r, ok := singleRune(toks[n].Text); ok && IsRune(toks[n]) && id_part(r) {
+					return nil, nil
+				}
+			}
+			return lex()(toks, stk, ctx)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScanChars(t *testing.T) {
+	// 非法字节各占一个字符, 真正的 U+FFFD 占三个字节
+	nodes := ScanChars("a\xffé�")
+	want := []struct {
+		text       string
+		start, end int
+	}{{"a", 0, 1}, {"\xff", 1, 2}, {"é", 2, 4}, {"�", 4, 7}}
+	if len(nodes) != len(want) {
+		t.Fatalf("got %d nodes", len(nodes))
+	}
+	for i, w := range want {
+		if n := nodes[i]; !IsRune(n) || n.Text != w.text || n.Start != w.start || n.End != w.end {
+			t.Errorf("node %d = %q %d-%d, want %q %d-%d", i, n.Text, n.Start, n.End, w.text, w.start, w.end)
+		}
+		// 所有节点共用同一个输入
+		if nodes[i].Ctx != nodes[0].Ctx {
+			t.Errorf("node %d does not share the source", i)
+		}
+	}
+}
+
+func TestCharCombinators(t *testing.T) {
+	SetParameters()
+	ident := Lexeme("id", Regex(`[a-z]+(-[a-z]+)*`))
+	expr := B["@seq"](Spaces, ident, S["$$"]("-"), ident)
+	if got, _ := Eval(expr, ScanChars(" a-b - c")); dumps(got) != "id:a-b token:- id:c" {
+		t.Errorf("got %s", dumps(got))
+	}
+	for _, c := range []struct {
+		c    Combinator
+		src  string
+		want string
+	}{
+		{Char('x'), "xy", "rune:x"},
+		{CharClass("^0-9"), "a1", "rune:a"},
+		{CharClass("0-9"), "a1", "<nil>"},
+		{Str("->"), "->x", "token:->"},
+		{Str("->"), "-x", "<nil>"},
+		{Regex(`\d*`), "abc", "token:"},
+		{Lexeme("num", O["@+"](CharClass("0-9"))), "12  x", "num:12"},
+		// SELECT 不匹配 SELECTED 的开头
+		{S["$$"]("SELECT"), "SELECTED", "<nil>"},
+		{S["$$"]("SELECT"), "SELECT x", "token:SELECT"},
+	} {
+		if got, _ := Eval(c.c, ScanChars(c.src)); dumps(got) != c.want {
+			t.Errorf("%q: got %s, want %s", c.src, dumps(got), c.want)
+		}
+	}
+}
+
+// token 上的谓词在字符输入上按 Scan 的规则扫描出 token
+func TestMixTokenPreds(t *testing.T) {
+	SetParameters()
+	assign := B["@seq"](P["$pred"](Preds["id"]), S["@_"]("="), Lexeme("value", Regex(`[^;]*`)), S["@_"](";"), P["$pred"](Preds["number"]), Kind(StrType))
+	got, rest := Eval(assign, ScanChars(`x = a-b c ; 0x10 "s"`))
+	if dumps(got) != "token:x value:a-b c  number:0x10 str:s" || len(rest) != 0 {
+		t.Errorf("got %s, %d left", dumps(got), len(rest))
+	}
+	// 谓词看到的是整个 token, 不是其中的一部分
+	if got, _ := Eval(P["$pred"](Preds["token"]), ScanChars("ab-c d")); dumps(got) != "token:ab-c" {
+		t.Errorf("got %s", dumps(got))
+	}
+	if got, _ := Eval(P["$pred"](Preds["id"]), ScanChars("ab-c")); got != nil {
+		t.Errorf("got %s, want <nil>", dumps(got))
+	}
+	for _, src := range []string{" x", `"x`, "// x"} {
+		if got, _ := Eval(P["$pred"](Preds["token"]), ScanChars(src)); got != nil {
+			t.Errorf("%q: got %s, want <nil>", src, dumps(got))
+		}
+	}
+}
+
+func TestAnalyzeChars(t *testing.T) {
+	got := analyze(t, `
+top <- (@seq (@* ($regex "a*")) (@* ($regex "a+")) (@* ($lexeme x ($str "b"))) (@* ($lexeme y $spaces)))
+`)
+	want := []string{
+		`nullable-loop top: body of @* can succeed without consuming input`,
+		`nullable-loop top: body of @* can succeed without consuming input`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
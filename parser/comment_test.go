@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func withBlockComments(f func()) {
+	defer SetBlockComments([2]string{"#|", "|#"})
+	defer SetNestedComments(false)
+	SetParameters()
+	f()
+}
+
+func TestBlockComments(t *testing.T) {
+	withBlockComments(func() {
+		SetBlockComments([2]string{"/*", "*/"}, [2]string{"{-", "-}"}, [2]string{"/**", "**/"})
+		for _, c := range []struct{ src, want string }{
+			{"a /* b */ c", "token:a@0-1 comment:/* b */@2-9 token:c@10-11 "},
+			{"{- x -}a", "comment:{- x -}@0-7 token:a@7-8 "},
+			// 开始符号最长的优先
+			{"/** x */ y **/", "comment:/** x */ y **/@0-14 "},
+			// 不嵌套时第一个结束符号就结束
+			{"/* a /* b */ c */", "comment:/* a /* b */@0-12 token:c@13-14 token:*/@15-17 "},
+		} {
+			if got := scanDump(c.src); got != c.want {
+				t.Errorf("Scan(%q) = %s, want %s", c.src, got, c.want)
+			}
+		}
+		SetNestedComments(true)
+		if got, want := scanDump("/* a /* b */ c */ d"), "comment:/* a /* b */ c */@0-17 token:d@18-19 "; got != want {
+			t.Errorf("nested: got %s, want %s", got, want)
+		}
+		for _, src := range []string{"a /* b", "/* a /* b */"} {
+			if _, err := ScanErr(src); err == nil || !strings.HasSuffix(err.Error(), "unterminated comment") {
+				t.Errorf("ScanErr(%q) error = %v", src, err)
+			}
+		}
+	})
+}
+
+// SetCommentStart/End 修改自己的副本, 不影响传给 SetBlockComments 的切片
+func TestSetCommentStartEnd(t *testing.T) {
+	withBlockComments(func() {
+		styles := [][2]string{{"/*", "*/"}, {"{-", "-}"}}
+		SetBlockComments(styles...)
+		SetCommentStart("(*")
+		SetCommentEnd("*)")
+		if styles[0] != [2]string{"/*", "*/"} {
+			t.Errorf("caller's slice modified: %v", styles)
+		}
+		if got, want := scanDump("(* a *) {- b -}"), "comment:(* a *)@0-7 comment:{- b -}@8-15 "; got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+		SetBlockComments()
+		SetCommentStart("<!--")
+		SetCommentEnd("-->")
+		if got, want := scanDump("<!-- a -->b"), "comment:<!-- a -->@0-10 token:b@10-11 "; got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+}
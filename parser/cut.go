@@ -0,0 +1,85 @@
+package parser
+
+import "fmt"
+
+// 剪枝
+// --------------------------------------------
+//
+// @^ 之前的部分匹配成功后, 它的失败不再回溯到 @or 的其他分支, 而是作为 ParseError 抛出.
+// 例如 (@seq Open (@_ SELECT) (@^ Expr (@+ Expr) Close)),
+// 读到 `(SELECT` 后子句再出错就直接报告, 不会再去尝试 Func 等分支.
+//
+// ParseError 以 panic 的形式向上传递, 最终由 Eval/EvalErr 或错误恢复组合子 (@recover 等) 接住,
+// 接住时记下出错所在的具名规则 (Rule). 具名规则只在 ParseState 中记录规则栈, 不为此付出 defer 的开销.
+
+type ParseError struct {
+	Pos  int    // 出错 token 的起始偏移, 输入结束时为 -1
+	Rule string // 出错时所在的具名规则
+	Msg  string
+}
+
+func (e *ParseError) Error() string {
+	if e.Rule == "" {
+		return fmt.Sprintf("%d: %s", e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("%d: %s: %s", e.Pos, e.Rule, e.Msg)
+}
+
+// toks 开头的 token 不符合要求, 生成的解析器 (parsec-gen) 也用它报告错误
+func Unexpected(toks []*Node) *ParseError {
+	if len(toks) == 0 {
+		return &ParseError{Pos: -1, Msg: "unexpected end of input"}
+	}
+	return &ParseError{Pos: toks[0].Start, Msg: fmt.Sprintf("unexpected %q", toks[0].Text)}
+}
+
+// @^
+func AtCut(cs ...Combinator) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			nodes := make([]*Node, 0)
+			for _, c := range cs {
+				t, r := ApplyCheck(c, toks, stk, ctx)
+				if t == nil {
+					panic(Unexpected(toks))
+				}
+				nodes, toks = append(nodes, t...), r
+			}
+			return nodes, toks
+		}
+	}
+}
+
+// 运行 c, 接住其中抛出的 ParseError, 同时撤销状态的修改
+func try(c Combinator, toks []*Node, stk []*Pair, ctx interface{}) (t, r []*Node, err *ParseError) {
+	st, hasState := ctx.(*ParseState)
+	var s snapshot
+	var depth int
+	if hasState {
+		s, depth = st.save(), len(st.rules)
+	}
+	defer func() {
+		if x := recover(); x != nil {
+			e, ok := x.(*ParseError)
+			if !ok {
+				panic(x)
+			}
+			if hasState {
+				st.restore(s)
+				// 栈顶是出错时最内层的规则, 之后回到 try 所在的规则
+				if e.Rule == "" && len(st.rules) > 0 {
+					e.Rule = st.rules[len(st.rules)-1]
+				}
+				st.rules = st.rules[:depth]
+			}
+			t, r, err = nil, nil, e
+		}
+	}()
+	t, r = ApplyCheck(c, toks, stk, ctx)
+	return t, r, nil
+}
+
+// 同 Eval, 剪枝后的失败作为 error 返回
+func EvalErr(c Combinator, toks []*Node) ([]*Node, []*Node, error) {
+	return EvalState(c, toks, NewParseState(nil))
+}
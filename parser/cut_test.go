@@ -0,0 +1,56 @@
+package parser
+
+import "testing"
+
+func TestAtCut(t *testing.T) {
+	SetParameters()
+	// 读到 let 之后出错不再尝试 @or 的其他分支
+	let := B["@seq"](S["@_"]("let"), B["@^"](P["$pred"](Preds["id"]), S["@_"]("=")))
+	c := B["@or"](let, P["$pred"](Preds["token"]))
+	if got, _, err := EvalErr(c, Scan("let x =")); err != nil || dumps(got) != "token:x" {
+		t.Errorf("got %s %v", dumps(got), err)
+	}
+	if got, _, err := EvalErr(c, Scan("let 1 =")); got != nil || err == nil || err.Error() != `4: unexpected "1"` {
+		t.Errorf("got %s %v", dumps(got), err)
+	}
+	if _, _, err := EvalErr(c, Scan("let x")); err == nil || err.Error() != "-1: unexpected end of input" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+// 错误的规则名取出错时最内层的规则, 接住错误后规则栈回到原来的位置
+func TestParseErrorRule(t *testing.T) {
+	SetParameters()
+	name := Rule("name", B["@^"](P["$pred"](Preds["id"])))
+	let := Rule("let", B["@seq"](S["@_"]("let"), name, B["@^"](S["@_"]("="))))
+	st := NewParseState(nil)
+	if _, _, err := EvalState(let, Scan("let 1"), st); err == nil || err.(*ParseError).Rule != "name" {
+		t.Errorf("error = %v, want rule name", err)
+	}
+	if _, _, err := EvalState(let, Scan("let x"), st); err == nil || err.Error() != "-1: let: unexpected end of input" {
+		t.Errorf("error = %v", err)
+	}
+	if len(st.rules) != 0 {
+		t.Errorf("rules = %v after error", st.rules)
+	}
+	// 错误恢复之后外层规则出错, 规则名是外层的
+	stmts := Rule("stmts", B["@seq"](J["@recover-until"](let, S["$$"](";")), B["@^"](S["@_"](";"), S["@_"]("end"))))
+	if _, _, err := EvalState(stmts, Scan("let 1 ; ."), st); err == nil || err.Error() != `8: stmts: unexpected "."` {
+		t.Errorf("error = %v", err)
+	}
+}
+
+// parse-sql 的子句用 @^ 剪枝, 出错的子句报告规则名和位置, 而不是笼统地跳过整个子句
+func TestParseSQLCut(t *testing.T) {
+	got := ParseSQL(`(SELECT (FROM) a)`)
+	errs := Errors(got)
+	if len(errs) != 1 || errs[0].Text != `13: from: unexpected ")"` {
+		t.Fatalf("Errors = %v in %s", errs, dumps(got))
+	}
+	if _, _, err := EvalErr(Select, Scan(`(SELECT a)`)); err == nil || err.Error() != `9: select: unexpected ")"` {
+		t.Errorf("error = %v", err)
+	}
+	if _, _, err := EvalErr(Select, Scan(`(SELECT (FROM t))`)); err == nil || err.Error() != `16: select: unexpected ")"` {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package parser
+
+import "fmt"
+
+// 文法定义语言
+// --------------------------------------------
+//
+// 文法文本由若干条规则组成, 每条规则形如 `name <- body`, body 是一个 sexp,
+// 其中的运算符与 B/O/T/S/C/P/J/F 中的组合子同名:
+//
+//	expr   <- (@or select nonParens)
+//	select <- (@= select (@seq open (@_ SELECT) expr (@+ expr) close))
+//	open   <- (@or (@~ "(") (@~ "["))
+//	str    <- ($pred str)
+//
+// 原子的含义:
+//
+//	name          引用规则 name
+//	"text"        匹配文本为 text 的 token 或数字, 等同于 ($$ text)
+//	$fail ...     C 中的常量组合子
+//
+// 规则可以先引用后定义, `//` 开头的是注释.
+// 读取文法时使用 sexp 的扫描参数, 不会修改调用者的设置. 空文本得到没有规则的文法.
+
+// 文法中可以按名字引用的谓词
+var Preds = map[string]func(*Node) bool{
+	"token":     IsTokenType,
+	"str":       IsStrType,
+	"character": IsCharacter,
+	"id":        func(n *Node) bool { return IsTokenType(n) && IsId(n.Text) },
+	"number":    IsNumber,
+	"numeral":   IsNumber, // 同 number
+}
+
+const ruleArrow = "<-"
+
+// 具名规则的集合. 文法文本读入的规则在 Defs 中保留规则体, 供分析和代码生成使用;
+// Go 代码中用 Define 定义的规则没有规则体.
+type Grammar struct {
+	Names []string         // 规则名, 按定义顺序
+	Defs  map[string]*Node // 规则名 -> 规则体
+
+	rules map[string]Combinator
+	preds map[string]func(*Node) bool
+}
+
+func NewGrammar() *Grammar {
+	return &Grammar{
+		Defs:  make(map[string]*Node),
+		rules: make(map[string]Combinator),
+		preds: make(map[string]func(*Node) bool),
+	}
+}
+
+// 定义具名规则 name, 返回该规则
+func (g *Grammar) Define(name string, c Combinator) Combinator {
+	if _, ok := g.rules[name]; !ok {
+		g.Names = append(g.Names, name)
+	}
+	g.rules[name] = Rule(name, c)
+	return g.rules[name]
+}
+
+// 读取文法文本, preds 中的谓词优先于 Preds
+func LoadGrammar(src string, preds map[string]func(*Node) bool) (*Grammar, error) {
+	nodes, err := parseGrammarSource(src)
+	if err != nil {
+		return nil, fmt.Errorf("grammar: %v", err)
+	}
+	nodes = filter(negate(IsComment), nodes)
+
+	defs := make([]RuleDef, 0)
+	for len(nodes) > 0 {
+		if len(nodes) < 3 || !IsTokenType(nodes[0]) || !IsTokenType(nodes[1]) || nodes[1].Text != ruleArrow {
+			return nil, fmt.Errorf("grammar: %d: expected `name %s body`", nodes[0].Start, ruleArrow)
+		}
+		defs = append(defs, RuleDef{Name: nodes[0].Text, Body: nodes[2]})
+		nodes = nodes[3:]
+	}
+	return DeclareGrammar(preds, defs...)
+}
+
+// Go 代码中声明的规则. Body 与文法文本中的规则体相同, 可以用 Call, Lit, Sym 构造:
+//
+//	g, err := DeclareGrammar(nil,
+//		RuleDef{"list", Call("@.@", Sym("item"), Call("@_", Lit(",")))},
+//		RuleDef{"item", Call("$pred", Sym("id"))},
+//	)
+//
+// 与 Define 不同, 这样声明的规则保留了规则体, 可以用于分析和代码生成.
+type RuleDef struct {
+	Name string
+	Body *Node
+}
+
+// (op args...)
+func Call(op string, args ...*Node) *Node {
+	return &Node{Type: "sexp", Elts: append([]*Node{Sym(op)}, args...)}
+}
+
+// 字符串字面量, 如 "SELECT"
+func Lit(s string) *Node {
+	return &Node{Type: StrType, Text: s}
+}
+
+// 规则名, 运算符, 谓词名等原子
+func Sym(name string) *Node {
+	return &Node{Type: TokenType, Text: name}
+}
+
+// 用 defs 构造文法, 规则可以先引用后定义, preds 中的谓词优先于 Preds
+func DeclareGrammar(preds map[string]func(*Node) bool, defs ...RuleDef) (*Grammar, error) {
+	g := NewGrammar()
+	for name, pred := range Preds {
+		g.preds[name] = pred
+	}
+	for name, pred := range preds {
+		g.preds[name] = pred
+	}
+
+	for _, d := range defs {
+		if _, ok := g.Defs[d.Name]; ok {
+			return nil, fmt.Errorf("grammar: %d: rule %q redefined", d.Body.Start, d.Name)
+		}
+		g.Defs[d.Name] = d.Body
+	}
+
+	for _, d := range defs {
+		c, err := g.compile(d.Body)
+		if err != nil {
+			return nil, err
+		}
+		g.Define(d.Name, c)
+	}
+	return g, nil
+}
+
+// 文法文本按 sexp 扫描. 运算符和自定义 token 会把 `<-` 等拆开, 缩进等设置也会改变扫描结果,
+// 扫描时使用固定的参数, 结束后恢复调用者的设置
+func parseGrammarSource(src string) ([]*Node, error) {
+	defer saveScanParameters()()
+	SetOperators()
+	SetTokenRules()
+	SetBlockComments([2]string{"#|", "|#"})
+	SetNestedComments(false)
+	SetRawQuotationMarks()
+	SetDoubledQuoteEscape(false)
+	SetSignificantWhitespaces()
+	SetIndentMode(false)
+	return ParseSexpErr(src)
+}
+
+// 按名字取规则
+func (g *Grammar) Get(name string) Combinator {
+	return g.rules[name]
+}
+
+// 从规则 start 开始解析 s
+func (g *Grammar) Parse(start, s string) []*Node {
+	c := g.Get(start)
+	if c == nil {
+		return nil
+	}
+	t, _ := Eval(c, Scan(s))
+	return t
+}
+
+// 引用规则 name, 求值时才查找, 因此允许递归和先引用后定义
+func (g *Grammar) Ref(name string) Combinator {
+	return func() Parser {
+		return g.rules[name]()
+	}
+}
+
+func (g *Grammar) compile(n *Node) (Combinator, error) {
+	switch {
+	case IsStrType(n):
+		return S["$$"](n.Text), nil
+	case n.Type == "sexp":
+		return g.compileList(filter(negate(IsComment), n.Elts), n.Start)
+	case IsTokenType(n):
+		if c, ok := C[n.Text]; ok {
+			return c, nil
+		}
+		if _, ok := g.Defs[n.Text]; !ok {
+			return nil, fmt.Errorf("grammar: %d: undefined rule %q", n.Start, n.Text)
+		}
+		return g.Ref(n.Text), nil
+	}
+	return nil, fmt.Errorf("grammar: %d: unexpected %s %q", n.Start, n.Type, n.Text)
+}
+
+func (g *Grammar) compileAll(ns []*Node) ([]Combinator, error) {
+	cs := make([]Combinator, 0, len(ns))
+	for _, n := range ns {
+		c, err := g.compile(n)
+		if err != nil {
+			return nil, err
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
+
+// 类型名, 谓词名等位置上的原子, 可以写成 token 或字符串
+func atomText(n *Node) (string, bool) {
+	if IsTokenType(n) || IsStrType(n) {
+		return n.Text, true
+	}
+	return "", false
+}
+
+func (g *Grammar) compileList(elts []*Node, pos int) (Combinator, error) {
+	if len(elts) == 0 || !IsTokenType(elts[0]) {
+		return nil, fmt.Errorf("grammar: %d: expected operator", pos)
+	}
+	op, args := elts[0].Text, elts[1:]
+	arity := func(n int) error {
+		if len(args) != n {
+			return fmt.Errorf("grammar: %d: %s expects %d arguments, got %d", pos, op, n, len(args))
+		}
+		return nil
+	}
+
+	if f, ok := B[op]; ok {
+		cs, err := g.compileAll(args)
+		if err != nil {
+			return nil, err
+		}
+		return f(cs...), nil
+	}
+	if f, ok := O[op]; ok {
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		c, err := g.compile(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return f(c), nil
+	}
+	if f, ok := T[op]; ok {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("grammar: %d: %s expects a type name", pos, op)
+		}
+		tp, ok := atomText(args[0])
+		if !ok {
+			return nil, fmt.Errorf("grammar: %d: %s expects a type name", pos, op)
+		}
+		cs, err := g.compileAll(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		return f(tp, cs...), nil
+	}
+	if f, ok := S[op]; ok {
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		s, ok := atomText(args[0])
+		if !ok {
+			return nil, fmt.Errorf("grammar: %d: %s expects a string", pos, op)
+		}
+		return f(s), nil
+	}
+	if f, ok := P[op]; ok {
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		name, _ := atomText(args[0])
+		pred, ok := g.preds[name]
+		if !ok {
+			return nil, fmt.Errorf("grammar: %d: undefined predicate %q", pos, name)
+		}
+		return f(pred), nil
+	}
+	if f, ok := J[op]; ok {
+		if err := arity(2); err != nil {
+			return nil, err
+		}
+		cs, err := g.compileAll(args)
+		if err != nil {
+			return nil, err
+		}
+		return f(cs[0], cs[1]), nil
+	}
+	if f, ok := F[op]; ok {
+		if err := arity(3); err != nil {
+			return nil, err
+		}
+		tp, ok := atomText(args[0])
+		if !ok {
+			return nil, fmt.Errorf("grammar: %d: %s expects a type name", pos, op)
+		}
+		cs, err := g.compileAll(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		return f(tp, cs[0], cs[1]), nil
+	}
+	return nil, fmt.Errorf("grammar: %d: unknown operator %q", pos, op)
+}
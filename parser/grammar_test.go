@@ -0,0 +1,167 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+// 节点的紧凑写法, 如 (select token:a (int number:1))
+func dump(n *Node) string {
+	if n == nil {
+		return "<nil>"
+	}
+	if n.Elts == nil && n.Type != "sexp" {
+		return n.Type + ":" + n.Text
+	}
+	s := "(" + n.Type
+	if n.Op != "" {
+		s += "[" + n.Op + "]"
+	}
+	for _, e := range n.Elts {
+		s += " " + dump(e)
+	}
+	return s + ")"
+}
+
+func dumps(ns []*Node) string {
+	if ns == nil {
+		return "<nil>"
+	}
+	ss := make([]string, len(ns))
+	for i, n := range ns {
+		ss[i] = dump(n)
+	}
+	return strings.Join(ss, " ")
+}
+
+const testGrammar = `
+// 以 sexp 形式写的 select 语句
+expr   <- (@or select call atom)
+select <- (@= select (@seq open (@_ SELECT) expr (@* expr) close))
+call   <- (@= call (@seq open name (@* expr) close))
+atom   <- (@or name ($pred number) ($pred str))
+name   <- ($pred id)
+open   <- (@or (@~ "(") (@~ "["))
+close  <- (@or (@~ ")") (@~ "]"))
+`
+
+func TestLoadGrammar(t *testing.T) {
+	g, err := LoadGrammar(testGrammar, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(g.Names, " "), "expr select call atom name open close"; got != want {
+		t.Errorf("Names = %q, want %q", got, want)
+	}
+	for _, c := range []struct{ src, want string }{
+		{`a`, `token:a`},
+		{`(SELECT a (f 1 "s"))`, `(select token:a (call token:f number:1 str:s))`},
+		{`[SELECT (SELECT x)]`, `(select (select token:x))`},
+	} {
+		if got := dumps(g.Parse("expr", c.src)); got != c.want {
+			t.Errorf("Parse(%q) = %s, want %s", c.src, got, c.want)
+		}
+	}
+	if got := g.Parse("expr", `)`); got != nil {
+		t.Errorf("Parse(\")\") = %s, want <nil>", dumps(got))
+	}
+	if g.Get("nothing") != nil || g.Parse("nothing", "a") != nil {
+		t.Error("undefined start rule should give nil")
+	}
+}
+
+func TestLoadGrammarPreds(t *testing.T) {
+	upper := func(n *Node) bool { return IsTokenType(n) && strings.ToUpper(n.Text) == n.Text }
+	g, err := LoadGrammar(`words <- (@+ ($pred upper))`, map[string]func(*Node) bool{"upper": upper})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := dumps(g.Parse("words", "A B c")), "token:A token:B"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestLoadGrammarErrors(t *testing.T) {
+	for _, c := range []struct{ src, want string }{
+		{`a <- b`, `undefined rule "b"`},
+		{`a <- "x"  a <- "y"`, `rule "a" redefined`},
+		{`a "x"`, "expected `name <- body`"},
+		{`a <- (@nothing "x")`, `unknown operator "@nothing"`},
+		{`a <- ($pred nothing)`, `undefined predicate "nothing"`},
+		{`a <- (@=)`, `@= expects a type name`},
+		{`a <- ($$ "x" "y")`, `$$ expects 1 arguments, got 2`},
+		{`a <- "x`, `unterminated string`},
+		{`a <- #| x`, `unterminated comment`},
+		{`a <- "x" ) b <- "y"`, `9: unexpected ")"`},
+		{`a <- (@or "x"`, `5: unexpected "("`},
+	} {
+		_, err := LoadGrammar(c.src, nil)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("LoadGrammar(%q) error = %v, want %q", c.src, err, c.want)
+		}
+	}
+	// 空文本和只有注释的文本没有规则
+	for _, src := range []string{"", "  ", "// x\n"} {
+		if g, err := LoadGrammar(src, nil); err != nil || len(g.Names) != 0 {
+			t.Errorf("LoadGrammar(%q) = %v, %v", src, g, err)
+		}
+	}
+	if got := ParseSexp(""); got != nil {
+		t.Errorf("ParseSexp(\"\") = %s", dumps(got))
+	}
+}
+
+// 读取文法不修改扫描参数
+func TestLoadGrammarParameters(t *testing.T) {
+	SetCalcParameters()
+	defer func() { SetOperators(); SetParameters() }()
+	SetLineComment("#")
+	if _, err := LoadGrammar("a <- \"x\" // y\n", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := dumps(Scan("a<=b # c\n")); got != "token:a token:<= token:b comment:# c" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestDeclareGrammar(t *testing.T) {
+	g, err := DeclareGrammar(nil,
+		RuleDef{Name: "list", Body: Call("@.@", Sym("item"), Call("@_", Lit(",")))},
+		RuleDef{Name: "item", Body: Call("$pred", Sym("id"))},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := dumps(g.Parse("list", "a , b , c d")), "token:a token:b token:c"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if g.Defs["item"] == nil {
+		t.Error("declared rules should keep their bodies")
+	}
+	if _, err := DeclareGrammar(nil, RuleDef{Name: "a", Body: Sym("b")}); err == nil {
+		t.Error("undefined rule should be an error")
+	}
+}
+
+func TestGrammarDefine(t *testing.T) {
+	SetParameters()
+	g := NewGrammar()
+	// 先引用后定义, 递归
+	g.Define("list", B["@seq"](S["@_"]("("), B["@*"](B["@or"](g.Ref("list"), g.Ref("atom"))), S["@_"](")")))
+	g.Define("atom", P["$pred"](Preds["id"]))
+	if got, want := strings.Join(g.Names, " "), "list atom"; got != want {
+		t.Errorf("Names = %q, want %q", got, want)
+	}
+	if got, want := dumps(g.Parse("list", "(a (b) c)")), "token:a token:b token:c"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// 同名的两个规则不共用缓存
+func TestRuleMemoIdentity(t *testing.T) {
+	SetParameters()
+	c := B["@or"](B["@seq"](Rule("x", S["$$"]("a")), C["$fail"]), Rule("x", S["$$"]("b")))
+	if got, _ := Eval(c, Scan("b")); dumps(got) != "token:b" {
+		t.Errorf("got %s, want token:b", dumps(got))
+	}
+}
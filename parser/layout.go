@@ -0,0 +1,145 @@
+package parser
+
+import "strings"
+
+// 缩进敏感的扫描
+// --------------------------------------------
+//
+// 开启 SetIndentMode 后, 扫描器像 Python 一样产生 newline, indent, dedent 三种 token:
+// 每个逻辑行结束时产生 newline, 缩进加深时产生 indent, 回到外层的缩进时每退出一层产生一个 dedent.
+// 空行和只有注释的行不影响缩进; 括号 ( [ { 内的换行不算行结束.
+// 输入结束时补上最后的 newline 和所有未闭合层次的 dedent.
+//
+//	server
+//	  host "a"
+//	  port 80
+//	log
+//
+// 扫描为 server newline indent host "a" newline port 80 newline dedent log newline.
+//
+// 缩进按原文比较: 深一层的缩进必须以外层的缩进为前缀, 因此同一层中混用 tab 和空格会报错.
+// SetTabWidth(n) 且 n > 0 时, tab 先展开到 n 的整数倍列再比较.
+// 第一行有缩进, 或回退到的缩进与外层都不一致时报 ScanError.
+// 缩进模式下 SetSignificantWhitespaces 不起作用, newline 只由这里的规则产生.
+
+var (
+	indent_mode = false
+	tab_width   = 0
+)
+
+func SetIndentMode(x bool) {
+	indent_mode = x
+}
+
+func SetTabWidth(n int) {
+	tab_width = n
+}
+
+type layout struct {
+	stack   []string // 各层的缩进, stack[0] 为 ""
+	parens  int      // 括号深度
+	pending bool     // 上一个 newline 之后是否已有 token
+	done    bool
+}
+
+func newLayout() *layout {
+	return &layout{stack: []string{""}}
+}
+
+func expandTabs(indent string) string {
+	if tab_width <= 0 {
+		return indent
+	}
+	var b strings.Builder
+	for _, r := range indent {
+		if r == '\t' {
+			b.WriteString(strings.Repeat(" ", tab_width-b.Len()%tab_width))
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// tok 之前需要插入的 token, prev 为上一个 token 的结束位置
+func (l *layout) before(s string, prev int, tok *Node) []*Node {
+	if l.done {
+		return nil
+	}
+	nodes := make([]*Node, 0)
+	if tok.Type == EofType {
+		l.done = true
+		if l.pending {
+			nodes = append(nodes, NewNode(NewlineType, tok.Start, tok.Start, nil, "", 0, nil))
+		}
+		for len(l.stack) > 1 {
+			l.stack = l.stack[:len(l.stack)-1]
+			nodes = append(nodes, NewNode(DedentType, tok.Start, tok.Start, nil, "", 0, nil))
+		}
+		return nodes
+	}
+	if IsComment(tok) {
+		return nodes
+	}
+	defer l.count(tok)
+
+	gap := s[prev:tok.Start]
+	nl := strings.IndexByte(gap, '\n')
+	if l.parens > 0 || nl == -1 && l.pending {
+		return nodes
+	}
+	if l.pending {
+		nodes = append(nodes, NewNode(NewlineType, prev+nl, prev+nl+1, nil, "", 0, nil))
+	}
+	indent := gap[strings.LastIndexByte(gap, '\n')+1:]
+	if !l.pending && indent != "" {
+		panic(&ScanError{Pos: tok.Start, Msg: "unexpected indent"})
+	}
+	cur := expandTabs(indent)
+	top := l.stack[len(l.stack)-1]
+	switch {
+	case cur == top:
+	case strings.HasPrefix(cur, top):
+		l.stack = append(l.stack, cur)
+		nodes = append(nodes, NewNode(IndentType, tok.Start, tok.Start, nil, indent, 0, nil))
+	default:
+		// 两者互不为前缀, 说明混用了 tab 和空格
+		mixed := !strings.HasPrefix(top, cur)
+		for len(l.stack) > 1 && top != cur && !strings.HasPrefix(cur, top) {
+			l.stack = l.stack[:len(l.stack)-1]
+			top = l.stack[len(l.stack)-1]
+			nodes = append(nodes, NewNode(DedentType, tok.Start, tok.Start, nil, "", 0, nil))
+		}
+		if top != cur {
+			msg := "unindent does not match any outer indentation level"
+			if mixed {
+				msg = "inconsistent use of tabs and spaces in indentation"
+			}
+			panic(&ScanError{Pos: tok.Start, Msg: msg})
+		}
+	}
+	return nodes
+}
+
+func (l *layout) count(tok *Node) {
+	l.pending = true
+	if IsTokenType(tok) {
+		if _, ok := bracketPairs[tok.Text]; ok {
+			l.parens++
+		} else if isBracketClose(tok.Text) && l.parens > 0 {
+			l.parens--
+		}
+	}
+}
+
+// 其中的换行不算行结束的括号
+var bracketPairs = map[string]string{"(": ")", "[": "]", "{": "}"}
+
+func isBracketClose(s string) bool {
+	for _, c := range bracketPairs {
+		if s == c {
+			return true
+		}
+	}
+	return false
+}
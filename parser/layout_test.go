@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func layoutDump(toks []*Node) string {
+	ss := make([]string, 0, len(toks))
+	for _, tok := range toks {
+		switch tok.Type {
+		case NewlineType, IndentType, DedentType:
+			ss = append(ss, tok.Type)
+		default:
+			ss = append(ss, tok.Text)
+		}
+	}
+	return strings.Join(ss, " ")
+}
+
+func withIndentMode(f func()) {
+	defer SetIndentMode(false)
+	defer SetTabWidth(0)
+	SetParameters()
+	SetIndentMode(true)
+	f()
+}
+
+func TestLayout(t *testing.T) {
+	withIndentMode(func() {
+		for _, c := range []struct{ src, want string }{
+			{"server\n  host \"a\"\n  port 80\nlog\n", "server newline indent host a newline port 80 newline dedent log newline"},
+			{"a\n  b\n    c\nd", "a newline indent b newline indent c newline dedent dedent d newline"},
+			{"a\n  b\n    c", "a newline indent b newline indent c newline dedent dedent"},
+			// 空行, 注释行, 括号内的换行不影响缩进
+			{"a\n\n  // x\n  b (c\nd)\ne", "a newline indent b ( c d ) newline dedent e newline"},
+			{"// c\na", "a newline"},
+			{"", ""},
+		} {
+			toks, err := ScanErr(c.src)
+			if err != nil {
+				t.Errorf("%q: %v", c.src, err)
+				continue
+			}
+			if got := layoutDump(filter(negate(IsComment), toks)); got != c.want {
+				t.Errorf("%q:\ngot  %s\nwant %s", c.src, got, c.want)
+			}
+		}
+	})
+}
+
+func TestLayoutErrors(t *testing.T) {
+	withIndentMode(func() {
+		for _, c := range []struct{ src, want string }{
+			{"  a\nb", "2: unexpected indent"},
+			{"\n\t a", "3: unexpected indent"},
+			{"a\n    b\n  c", "10: unindent does not match any outer indentation level"},
+			{"a\n  b\n\tc", "7: inconsistent use of tabs and spaces in indentation"},
+		} {
+			if _, err := ScanErr(c.src); err == nil || err.Error() != c.want {
+				t.Errorf("%q: error = %v, want %s", c.src, err, c.want)
+			}
+		}
+		SetTabWidth(4)
+		if toks, err := ScanErr("a\n    b\n\tc"); err != nil || layoutDump(toks) != "a newline indent b newline c newline dedent" {
+			t.Errorf("tab width: %s %v", layoutDump(toks), err)
+		}
+	})
+}
+
+// 缩进模式下 significant whitespaces 不再产生 newline
+func TestLayoutSignificantWhitespaces(t *testing.T) {
+	defer SetSignificantWhitespaces()
+	SetParameters()
+	SetSignificantWhitespaces("\n")
+	if got := layoutDump(Scan("a\nb")); got != "a newline b" {
+		t.Errorf("got %s", got)
+	}
+	withIndentMode(func() {
+		if got := layoutDump(Scan("a\n  b\n")); got != "a newline indent b newline dedent" {
+			t.Errorf("got %s", got)
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package parser
+
+// 运算符优先级表
+// --------------------------------------------
+//
+// OperatorTable 按优先级从高到低列出各层运算符, Build 以 term 为操作数,
+// 用优先级爬升 (Pratt) 的方式生成整个表达式的解析器, 不必为每一层写一个函数.
+// 生成的节点与 @infix-left, @infix-right, @prefix, @postfix 相同:
+//
+//	(tp 左操作数 运算符 右操作数)  (tp 运算符 操作数)  (tp 操作数 运算符)
+//
+// 用 With 可以指定其他形状, 见 Builder.
+//
+// 例如
+//
+//	expr := OperatorTable{
+//		{PostfixOp("postfix", op("++"))},
+//		{PrefixOp("prefix", op("-"))},
+//		{InfixLeftOp("multiplicative", op("*")), InfixLeftOp("multiplicative", op("/"))},
+//		{InfixLeftOp("additive", op("+")), InfixLeftOp("additive", op("-"))},
+//		{InfixRightOp("assign", op("="))},
+//	}.Build(term)
+//
+// 同一位置可以匹配多个运算符时, 先尝试优先级高的, 同一层中按声明顺序.
+// 运算符匹配后操作数不匹配时, 回溯到运算符之前.
+// 不结合的运算符 (InfixNoneOp) 在同一层连用时抛出 ParseError, 而不是在第一个运算符之后停止.
+
+type Operator struct {
+	Kind     string // infix-left, infix-right, infix-none, prefix, postfix, mixfix, mixfix-prefix
+	Type     string // 生成节点的类型
+	Op       Combinator
+	Keywords []Combinator // 混合运算符的各个关键字
+	Builder  Builder      // 构造节点, nil 时同 TripleBuilder, 混合运算符不使用
+}
+
+// 用 b 构造这个运算符的节点, Op 须恰好产生一个节点, 否则不使用 b
+func (o Operator) With(b Builder) Operator {
+	o.Builder = b
+	return o
+}
+
+func InfixLeftOp(tp string, op Combinator) Operator {
+	return Operator{Kind: "infix-left", Type: tp, Op: op}
+}
+
+func InfixRightOp(tp string, op Combinator) Operator {
+	return Operator{Kind: "infix-right", Type: tp, Op: op}
+}
+
+// 不结合的中缀运算符, a < b < c 抛出 ParseError
+func InfixNoneOp(tp string, op Combinator) Operator {
+	return Operator{Kind: "infix-none", Type: tp, Op: op}
+}
+
+func PrefixOp(tp string, op Combinator) Operator {
+	return Operator{Kind: "prefix", Type: tp, Op: op}
+}
+
+func PostfixOp(tp string, op Combinator) Operator {
+	return Operator{Kind: "postfix", Type: tp, Op: op}
+}
+
+// 混合运算符: 左操作数 kw1 e1 kw2 e2 ... kwn en, 如
+//
+//	MixfixOp("conditional", S["@~"]("?"), S["@~"](":"))         a ? b : c
+//	MixfixOp("between", S["@_"]("BETWEEN"), S["@_"]("AND"))     x BETWEEN a AND b
+//
+// e1 ... en 可以包含同一层和更高优先级的运算符, 因此是右结合的.
+// 节点为 (tp 左操作数 kw1 e1 ... kwn en), 其中的 phantom 节点被去掉.
+func MixfixOp(tp string, keywords ...Combinator) Operator {
+	return Operator{Kind: "mixfix", Type: tp, Keywords: keywords}
+}
+
+// 以关键字开头的混合运算符: kw1 e1 kw2 e2 ... kwn en, 如
+//
+//	PrefixMixfixOp("if", S["@_"]("if"), S["@_"]("then"), S["@_"]("else"))
+func PrefixMixfixOp(tp string, keywords ...Combinator) Operator {
+	return Operator{Kind: "mixfix-prefix", Type: tp, Keywords: keywords}
+}
+
+type OperatorTable [][]Operator
+
+func (table OperatorTable) Build(term Combinator) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			p := &pratt{table: table, term: term, stk: stk, ctx: ctx}
+			return p.expr(toks, len(table)-1)
+		}
+	}
+}
+
+type pratt struct {
+	table OperatorTable
+	term  Combinator
+	stk   []*Pair
+	ctx   interface{}
+}
+
+func opNode(tp string, parts ...[]*Node) *Node {
+	elts := make([]*Node, 0)
+	for _, part := range parts {
+		elts = append(elts, part...)
+	}
+	n := &Node{Type: tp, Elts: elts}
+	if len(elts) > 0 {
+		n.Start, n.End = elts[0].Start, elts[len(elts)-1].End
+	}
+	return n
+}
+
+// 运算符只有一个节点时才交给 Builder, 否则 (如 S["@_"] 不产生节点) 按 TripleBuilder 的形状拼接
+func (o Operator) node(kind string, op []*Node, operands ...[]*Node) *Node {
+	if o.Builder == nil || len(op) != 1 {
+		switch kind {
+		case "infix":
+			return opNode(o.Type, operands[0], op, operands[1])
+		case "prefix":
+			return opNode(o.Type, op, operands[0])
+		default:
+			return opNode(o.Type, operands[0], op)
+		}
+	}
+	args := make([]*Node, 0, len(operands))
+	for _, e := range operands {
+		args = append(args, e...)
+	}
+	return o.Builder(kind, o.Type, op[0], args)
+}
+
+// 只使用第 0 到 level 层 (优先级不低于 level) 的运算符
+func (p *pratt) expr(toks []*Node, level int) ([]*Node, []*Node) {
+	left, r := p.prefix(toks, level)
+	if left == nil {
+		return nil, nil
+	}
+	nonassoc := -1
+loop:
+	for len(r) > 0 {
+		for i := 0; i <= level; i++ {
+			for _, o := range p.table[i] {
+				switch o.Kind {
+				case "prefix", "mixfix-prefix":
+					continue
+				case "mixfix":
+					if parts, r1 := p.mixfix(o, r, i); parts != nil {
+						left, r = []*Node{mixfixNode(o.Type, left, parts)}, r1
+						continue loop
+					}
+					continue
+				}
+				t, r1 := ApplyCheck(o.Op, r, p.stk, p.ctx)
+				if t == nil {
+					continue
+				}
+				if o.Kind == "infix-none" && i == nonassoc {
+					panic(NonAssociative(r[0]))
+				}
+				if o.Kind == "postfix" {
+					left, r = []*Node{o.node("postfix", t, left)}, r1
+					continue loop
+				}
+				// 左结合和不结合时右操作数只能包含更高优先级的运算符
+				next := i - 1
+				if o.Kind == "infix-right" {
+					next = i
+				}
+				right, r2 := p.expr(r1, next)
+				if right == nil {
+					continue
+				}
+				left, r = []*Node{o.node("infix", t, left, right)}, r2
+				if o.Kind == "infix-none" {
+					nonassoc = i
+				}
+				continue loop
+			}
+		}
+		break
+	}
+	return left, r
+}
+
+// 前缀运算符和操作数
+func (p *pratt) prefix(toks []*Node, level int) ([]*Node, []*Node) {
+	for i := 0; i <= level; i++ {
+		for _, o := range p.table[i] {
+			if o.Kind == "mixfix-prefix" {
+				if parts, r := p.mixfix(o, toks, i); parts != nil {
+					// 开头的关键字可能不产生节点
+					n := mixfixNode(o.Type, parts)
+					n.Start = toks[0].Start
+					return []*Node{n}, r
+				}
+				continue
+			}
+			if o.Kind != "prefix" {
+				continue
+			}
+			t, r := ApplyCheck(o.Op, toks, p.stk, p.ctx)
+			if t == nil {
+				continue
+			}
+			if operand, r := p.expr(r, i); operand != nil {
+				return []*Node{o.node("prefix", t, operand)}, r
+			}
+		}
+	}
+	return ApplyCheck(p.term, toks, p.stk, p.ctx)
+}
+
+// 关键字和它们后面的操作数, 失败时返回 nil
+func (p *pratt) mixfix(o Operator, toks []*Node, level int) ([]*Node, []*Node) {
+	parts, r := make([]*Node, 0), toks
+	for _, kw := range o.Keywords {
+		t, r1 := ApplyCheck(kw, r, p.stk, p.ctx)
+		if t == nil {
+			return nil, nil
+		}
+		e, r2 := p.expr(r1, level)
+		if e == nil {
+			return nil, nil
+		}
+		parts, r = append(append(parts, t...), e...), r2
+	}
+	return parts, r
+}
+
+func mixfixNode(tp string, parts ...[]*Node) *Node {
+	n := opNode(tp, parts...)
+	n.Elts = filter(negate(IsPhantom), n.Elts)
+	return n
+}
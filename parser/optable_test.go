@@ -0,0 +1,110 @@
+package parser
+
+import "testing"
+
+func tableDump(table OperatorTable, src string) (string, int, error) {
+	term := P["$pred"](func(n *Node) bool { return IsNumber(n) || IsTokenType(n) && IsId(n.Text) })
+	got, rest, err := EvalErr(table.Build(term), Scan(src))
+	return dumps(got), len(rest), err
+}
+
+func TestOperatorTable(t *testing.T) {
+	SetCalcParameters()
+	defer func() { SetOperators(); SetParameters() }()
+	table := OperatorTable{
+		{PostfixOp("post", op("++"))},
+		{PrefixOp("pre", op("-")), PrefixOp("pre", op("!"))},
+		{InfixLeftOp("mul", op("*"))},
+		{InfixLeftOp("add", op("+")), InfixLeftOp("add", op("-"))},
+		{InfixRightOp("pow", op("^"))},
+	}
+	for _, c := range []struct{ src, want string }{
+		{"1 + 2 * 3", "(add number:1 token:+ (mul number:2 token:* number:3))"},
+		{"1 * 2 + 3", "(add (mul number:1 token:* number:2) token:+ number:3)"},
+		{"1 - 2 + 3", "(add (add number:1 token:- number:2) token:+ number:3)"},
+		{"a ^ b ^ c", "(pow token:a token:^ (pow token:b token:^ token:c))"},
+		{"- a ++ * b", "(mul (pre token:- (post token:a token:++)) token:* token:b)"},
+		{"! - a", "(pre token:! (pre token:- token:a))"},
+		{"a ++ ++", "(post (post token:a token:++) token:++)"},
+		{"a", "token:a"},
+	} {
+		if got, _, err := tableDump(table, c.src); got != c.want || err != nil {
+			t.Errorf("%q: got %s %v, want %s", c.src, got, err, c.want)
+		}
+	}
+	// 运算符后的操作数不匹配时回溯到运算符之前
+	if got, rest, _ := tableDump(table, "a + b *"); got != "(add token:a token:+ token:b)" || rest != 1 {
+		t.Errorf("got %s (%d left)", got, rest)
+	}
+	if got, _, _ := tableDump(table, "+ a"); got != "<nil>" {
+		t.Errorf("got %s, want failure", got)
+	}
+}
+
+// 不结合的运算符连用时报错, 而不是在第一个运算符之后停止
+func TestOperatorInfixNone(t *testing.T) {
+	SetCalcParameters()
+	defer func() { SetOperators(); SetParameters() }()
+	table := OperatorTable{
+		{InfixLeftOp("add", op("+"))},
+		{InfixNoneOp("rel", op("<")), InfixNoneOp("rel", op(">"))},
+		{InfixLeftOp("and", op("&&"))},
+	}
+	if got, _, err := tableDump(table, "a + b < c && c > d"); err != nil || got != "(and (rel (add token:a token:+ token:b) token:< token:c) token:&& (rel token:c token:> token:d))" {
+		t.Errorf("got %s %v", got, err)
+	}
+	if _, _, err := tableDump(table, "a < b + c > d"); err == nil || err.Error() != `10: non-associative operator ">" cannot be chained` {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestAtInfixNone(t *testing.T) {
+	SetCalcParameters()
+	defer func() { SetOperators(); SetParameters() }()
+	c := F["@infix-none"]("rel", P["$pred"](IsNumber), B["@or"](op("<"), op(">")))
+	if got, _, err := EvalErr(c, Scan("1 < 2")); err != nil || dumps(got) != "(rel number:1 token:< number:2)" {
+		t.Errorf("got %s %v", dumps(got), err)
+	}
+	_, _, err := EvalErr(c, Scan("1 < 2 > 3"))
+	if e, ok := err.(*ParseError); !ok || e.Pos != 6 || e.Error() != `6: non-associative operator ">" cannot be chained` {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestOperatorMixfix(t *testing.T) {
+	SetCalcParameters()
+	defer func() { SetOperators(); SetParameters() }()
+	table := OperatorTable{
+		{InfixLeftOp("add", op("+"))},
+		{MixfixOp("cond", S["@~"]("?"), S["@~"](":"))},
+		{MixfixOp("between", S["@_"]("BETWEEN"), S["@_"]("AND"))},
+		{PrefixMixfixOp("if", S["@_"]("if"), S["@_"]("then"), S["@_"]("else"))},
+	}
+	for _, c := range []struct{ src, want string }{
+		{"a ? b + c : d", "(cond token:a (add token:b token:+ token:c) token:d)"},
+		{"a ? b : c ? d : e", "(cond token:a token:b (cond token:c token:d token:e))"},
+		{"x BETWEEN a AND b + c", "(between token:x token:a (add token:b token:+ token:c))"},
+		{"if a then b else c ? d : e", "(if token:a token:b (cond token:c token:d token:e))"},
+	} {
+		if got, _, err := tableDump(table, c.src); got != c.want || err != nil {
+			t.Errorf("%q: got %s %v, want %s", c.src, got, err, c.want)
+		}
+	}
+	// 缺少后面的关键字时回溯到 ? 之前
+	if got, rest, _ := tableDump(table, "a ? b"); got != "token:a" || rest != 2 {
+		t.Errorf("got %s (%d left)", got, rest)
+	}
+}
+
+func TestCalcRelational(t *testing.T) {
+	defer func() { SetOperators(); SetParameters() }()
+	if got := dumps(ParseCalc("1 < 2 == 3 > 4")); got != "(equality (relational (int number:1) token:< (int number:2)) token:== (relational (int number:3) token:> (int number:4)))" {
+		t.Errorf("got %s", got)
+	}
+	if got := ParseCalc("1 < 2 < 3"); got != nil {
+		t.Errorf("got %s, want failure", dumps(got))
+	}
+	if _, _, err := EvalErr(O["::"](conditionalExpression), Scan("1 < 2 >= 3")); err == nil || err.Error() != `6: non-associative operator ">=" cannot be chained` {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -1,7 +1,8 @@
 package parser
 
+import "math/big"
+
 func SetCalcParameters() {
-	SetWhiteSpace(" ", "\n")
 	SetDelims("(", ")", "[", "]")
 	SetOperators("==", "!=", ">=", "<=", "&&", "||", ">>", "<<", "++", "--",
 				"+", "-", "*", "/", "%", "~", "!", ":", "?", ">", "<", "|", "^", "&")
@@ -11,229 +12,59 @@ func SetCalcParameters() {
 // utility for constructing operators
 func op(s string) Combinator { return S["$$"](s) }
 
-// ?:	 Ternary conditional
-// --------------------------------------------
-//
-//	 conditionalExpression ::
-//		logicalOrExpression `?` conditionalExpression `:` conditionalExpression
-//		| logicalOrExpression
-func conditionalExpression() Parser {
-	return B["@or"](
-		T["@="](Conditional,
-			O["::"](logicalOrExpression),
-			S["@~"]("?"), O["::"](conditionalExpression),
-			S["@~"](":"), O["::"](conditionalExpression)),
-
-		O["::"](logicalOrExpression))()
-}
-
-// ||	 Logical OR
-// --------------------------------------------
-//
-//	 logicalOrExpression ::
-//		logicalAndExpression `||` logicalAndExpression
-//		| logicalAndExpression
-func logicalOrExpression() Parser {
-	return B["@or"](
-		F["@infix-left"](LogicalOR,
-			O["::"](logicalAndExpression),
-			op("||")),
-
-		O["::"](logicalAndExpression))()
-}
-
-// &&	 Logical AND
-// --------------------------------------------
-//
-//	 logicalAndExpression ::
-//		bitwiseOrExpression `&&` bitwiseOrExpression
-//		| bitwiseOrExpression
-func logicalAndExpression() Parser {
-	return B["@or"](
-		F["@infix-left"](LogicalAND,
-			O["::"](bitwiseOrExpression),
-			op("&&")),
-
-		O["::"](bitwiseOrExpression))()
-}
-
-// |	 Bitwise OR (inclusive or)
-// --------------------------------------------
-//
-//	 bitwiseOrExpression ::
-//		bitwiseXorExpression `|` bitwiseXorExpression
-//		| bitwiseXorExpression
-func bitwiseOrExpression() Parser {
-	return B["@or"](
-		F["@infix-left"](BitwiseOR,
-			O["::"](bitwiseXorExpression),
-			op("|")),
-
-		O["::"](bitwiseXorExpression))()
-}
-
-// ^	 Bitwise XOR (exclusive or)
-// --------------------------------------------
-//
-//	 bitwiseXorExpression ::
-//		bitwiseAndExpression `^` bitwiseAndExpression
-//		| bitwiseAndExpression
-func bitwiseXorExpression() Parser {
-	return B["@or"](
-		F["@infix-left"](BitwiseXOR,
-			O["::"](bitwiseAndExpression),
-			op("^")),
-
-		O["::"](bitwiseAndExpression))()
-}
-
-// &	 Bitwise AND
-// --------------------------------------------
-//
-//	 bitwiseAndExpression ::
-//		equalityExpression `&` equalityExpression
-//		| equalityExpression
-func bitwiseAndExpression() Parser {
-	return B["@or"](
-		F["@infix-left"](BitwiseAND,
-			O["::"](equalityExpression),
-			op("&")),
-
-		O["::"](equalityExpression))()
-}
-
-// equality
-// --------------------------------------------
-//
-//	 equalityExpression ::
-//		relationalExpression `==` relationalExpression
-//		| relationalExpression `!=` relationalExpression
-//		| relationalExpression
-func equalityExpression() Parser {
-	return B["@or"](
-		F["@infix-left"](Equality,
-			O["::"](relationalExpression),
-			O["::"](equalityOperator)),
-
-		O["::"](relationalExpression))()
-}
-
-var equalityOperator = B["@or"](op("=="), op("!="))
-
-// relational
+// operators
 // --------------------------------------------
 //
-//	 relationalExpression ::
-//		BitwiseShiftExpression `<` BitwiseShiftExpression
-//		| BitwiseShiftExpression `<=` BitwiseShiftExpression
-//		| BitwiseShiftExpression `>` BitwiseShiftExpression
-//		| BitwiseShiftExpression `>=` BitwiseShiftExpression
-//		| BitwiseShiftExpression
-func relationalExpression() Parser {
-	return B["@or"](
-		F["@infix-left"](Relational,
-			O["::"](BitwiseShiftExpression),
-			O["::"](relationalOperator)),
-
-		O["::"](BitwiseShiftExpression))()
-}
-
-var relationalOperator = B["@or"](op("<"), op("<="), op(">"), op(">="))
-
-// bitwise shift
-// --------------------------------------------
+// 优先级从高到低. prefix 和 postfix 是一元运算符, relational 不结合, conditional 右结合, 其余都是左结合
 //
-//	 BitwiseShiftExpression ::
-//		additiveExpression `<<` additiveExpression
-//		| additiveExpression `>>` additiveExpression
-//		| additiveExpression
-func BitwiseShiftExpression() Parser {
-	return B["@or"](
-		F["@infix-left"](BitwiseShift,
-			O["::"](additiveExpression),
-			O["::"](BitwiseShiftOperator)),
-
-		O["::"](additiveExpression))()
+//	postfix          ++ --
+//	prefix           ++ -- + - ~ !
+//	multiplicative   * / %
+//	additive         + -
+//	bitwise-shift    << >>
+//	relational       <= >= < >  不结合, a < b < c 抛出 ParseError
+//	equality         == !=
+//	bitwise-and      &
+//	bitwise-xor      ^
+//	bitwise-or       |
+//	logical-and      &&
+//	logical-or       ||
+//	conditional      ?:  右结合
+var calcOperators = OperatorTable{
+	{PostfixOp(Postfix, postfixOperator)},
+	{PrefixOp(Prefix, prefixOperator)},
+	{InfixLeftOp(Multiplicative, multiplicativeOperator)},
+	{InfixLeftOp(Additive, additiveOperator)},
+	{InfixLeftOp(BitwiseShift, BitwiseShiftOperator)},
+	{InfixNoneOp(Relational, relationalOperator)},
+	{InfixLeftOp(Equality, equalityOperator)},
+	{InfixLeftOp(BitwiseAND, op("&"))},
+	{InfixLeftOp(BitwiseXOR, op("^"))},
+	{InfixLeftOp(BitwiseOR, op("|"))},
+	{InfixLeftOp(LogicalAND, op("&&"))},
+	{InfixLeftOp(LogicalOR, op("||"))},
+	{MixfixOp(Conditional, S["@~"]("?"), S["@~"](":"))},
 }
 
-var BitwiseShiftOperator = B["@or"](op("<<"), op(">>"))
-
-// additive
-// --------------------------------------------
-//
-//	 additiveExpression ::
-//		multiplicativeExpression `+` multiplicativeExpression
-//		| multiplicativeExpression `-` multiplicativeExpression
-//		| multiplicativeExpression
-func additiveExpression() Parser {
-	return B["@or"](
-		F["@infix-left"](Additive,
-			O["::"](multiplicativeExpression),
-			O["::"](additiveOperator)),
-
-		O["::"](multiplicativeExpression))()
-}
-
-var additiveOperator = B["@or"](op("+"), op("-"))
-
-// multiplicative
-// --------------------------------------------
-//
-//	 multiplicativeExpression ::
-//		prefixExpression `*` prefixExpression
-//		| prefixExpression `/` prefixExpression
-//		| prefixExpression `%` prefixExpression
-//		| prefixExpression
-func multiplicativeExpression() Parser {
-	return B["@or"](
-		F["@infix-left"](Multiplicative,
-			O["::"](prefixExpression),
-			O["::"](multiplicativeOperator)),
-
-		O["::"](prefixExpression))()
+//	 conditionalExpression ::
+//		operators over primaryExpression
+func conditionalExpression() Parser {
+	return calcOperators.Build(O["::"](primaryExpression))()
 }
 
-var multiplicativeOperator = B["@or"](op("*"), op("/"), op("%"))
+var equalityOperator = Rule("equalityOperator", B["@or"](op("=="), op("!=")))
 
-// prefix
-// --------------------------------------------
-//
-//	 prefixExpression ::
-//		`++` postfixExpression
-//		| `--` postfixExpression
-//		| `+` postfixExpression
-//		| `-` postfixExpression
-//		| `~` postfixExpression
-//		| `!` postfixExpression
-//		| postfixExpression
-func prefixExpression() Parser {
-	return B["@or"](
-		F["@prefix"](Prefix,
-			O["::"](postfixExpression),
-			O["::"](prefixOperator)),
+var relationalOperator = Rule("relationalOperator", B["@or"](op("<="), op(">="), op("<"), op(">")))
 
-		O["::"](postfixExpression))()
-}
+var BitwiseShiftOperator = Rule("BitwiseShiftOperator", B["@or"](op("<<"), op(">>")))
 
-var prefixOperator = B["@or"](op("++"), op("--"), op("+"), op("-"), op("~"), op("!"))
+var additiveOperator = Rule("additiveOperator", B["@or"](op("+"), op("-")))
 
-// postfix
-// --------------------------------------------
-//
-//	 postfixExpression ::
-//		primaryExpression `++`
-//		| primaryExpression `--`
-//		| primaryExpression
-func postfixExpression() Parser {
-	return B["@or"](
-		F["@postfix"](Postfix,
-			O["::"](primaryExpression),
-			O["::"](postfixOperator)),
+var multiplicativeOperator = Rule("multiplicativeOperator", B["@or"](op("*"), op("/"), op("%")))
 
-		O["::"](primaryExpression))()
-}
+var prefixOperator = Rule("prefixOperator", B["@or"](op("++"), op("--"), op("+"), op("-"), op("~"), op("!")))
 
-var postfixOperator = B["@or"](op("++"), op("--"))
+var postfixOperator = Rule("postfixOperator", B["@or"](op("++"), op("--")))
 
 // primary
 // --------------------------------------------
@@ -250,7 +81,7 @@ func primaryExpression() Parser {
 //		| stringLiteral
 //		| intLiteral
 //		| floatLiteral
-var literal = B["@or"](boolLiteral, stringLiteral, intLiteral, floatLiteral)
+var literal = Rule("literal", B["@or"](boolLiteral, stringLiteral, intLiteral, floatLiteral))
 
 //	 boolLiteral ::
 //		`true` | `false`
@@ -259,11 +90,19 @@ var boolLiteral = T["@="](Bool, B["@or"](S["$$"]("true"), S["$$"]("false")))
 var stringLiteral = P["$pred"](IsStrType)
 
 var intLiteral = T["@="](Int, P["$pred"](func(node *Node) bool {
-	return IsTokenType(node) && IsNumeral(node.Text) && !HasDot(node.Text)
+	switch node.Value.(type) {
+	case int64, *big.Int:
+		return IsNumber(node)
+	}
+	return false
 }))
 
 var floatLiteral = T["@="](Float, P["$pred"](func(node *Node) bool {
-	return IsTokenType(node) && IsNumeral(node.Text) && HasDot(node.Text)
+	switch node.Value.(type) {
+	case float64, *big.Float:
+		return IsNumber(node)
+	}
+	return false
 }))
 
 var identifier = P["$pred"](func(node *Node) bool { return IsTokenType(node) && IsId(node.Text) })
@@ -288,25 +127,6 @@ const (
 	Float          string = "float"
 )
 
-func IsNumeral(s []rune) bool {
-	if len(s) == 0 {
-		return false
-	}
-	if IsDigit(s[0:1]) {
-		return true
-	}
-	return false
-}
-
-func HasDot(s []rune) bool {
-	for _, c := range s {
-		if c == '.' {
-			return true
-		}
-	}
-	return false
-}
-
 func ParseCalc(s string) []*Node {
 	SetCalcParameters()
 	t, _ := Eval(B["@*"](O["::"](conditionalExpression)), Scan(s))
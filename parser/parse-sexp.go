@@ -1,5 +1,7 @@
 package parser
 
+import "fmt"
+
 func SetParameters() {
 	SetDelims("(", ")", "[", "]", "{", "}", "'", "`", ",")
 	SetLineComment("//")
@@ -13,7 +15,7 @@ var NonParens = B["@and"](B["@!"](Open), B["@!"](Close))
 
 var Parens, Sexp Combinator
 
-func ParseSexp(s string) []*Node {
+func initSexp() {
 	Parens = func() Parser {
 		return T["@="]("sexp", B["@seq"](Open, B["@*"](Sexp), Close))()
 	}
@@ -21,6 +23,24 @@ func ParseSexp(s string) []*Node {
 		return O["@+"](B["@or"](Parens, NonParens))()
 	}
 	SetParameters()
+}
+
+func ParseSexp(s string) []*Node {
+	initSexp()
 	t, _ := Eval(Sexp, Scan(s))
 	return t
 }
+
+// 同 ParseSexp, 未结束的字符串, 注释等扫描错误和未读完的输入作为 error 返回
+func ParseSexpErr(s string) ([]*Node, error) {
+	initSexp()
+	toks, err := ScanErr(s)
+	if err != nil {
+		return nil, err
+	}
+	t, rest := Eval(Sexp, toks)
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%d: unexpected %q", rest[0].Start, rest[0].Text)
+	}
+	return t, nil
+}
@@ -0,0 +1,64 @@
+package parser
+
+var Select, Having, Group, Where, On, JOIN, As, From, Field, Desc, Aes, Order, Limit, Func, Expr Combinator
+
+// 各个子句都是具名规则, 跟踪时显示为 select, where 等
+// 关键字之后的部分用 @^ 剪枝, 出错的子句会被跳过并替换为 error 节点, 用 Errors 取出
+func ParseSQL(s string) []*Node {
+	Expr = Rule("expr", func() Parser {
+		return AtRecover(B["@or"](Select, Having, Group, Where, On, JOIN, As, From, Field, Desc, Aes, Order, Limit, Func, NonParens), "(", ")", "[", "]")()
+	})
+	Select = Rule("select", func() Parser {
+		return T["@="]("select", B["@seq"](Open, S["@_"]("SELECT"), B["@^"](Expr, O["@+"](Expr), Close)))()
+	})
+	Having = Rule("having", func() Parser {
+		return T["@="]("having", B["@seq"](Open, S["@_"]("HAVING"), B["@^"](Expr, O["@+"](Expr), Close)))()
+	})
+	Group = Rule("group", func() Parser {
+		return T["@="]("group", B["@seq"](Open, S["@_"]("GROUP"), B["@^"](Expr, O["@+"](Expr), Close)))()
+	})
+	Where = Rule("where", func() Parser {
+		return T["@="]("where", B["@seq"](Open, S["@_"]("WHERE"), B["@^"](Expr, O["@+"](Expr), Close)))()
+	})
+	On = Rule("on", func() Parser {
+		return T["@="]("on", B["@seq"](Open, S["@_"]("ON"), B["@^"](JOIN, O["@+"](Expr), Close)))()
+	})
+	JOIN = Rule("join", func() Parser {
+		return T["@="]("join", B["@seq"](Open, S["@_"]("JOIN"), B["@^"](Expr, O["@+"](Expr), Close)))()
+	})
+	As = Rule("as", func() Parser {
+		return T["@="]("as", B["@seq"](Open, S["@_"]("AS"), B["@^"](Expr, NonParens, Close)))()
+	})
+	From = Rule("from", func() Parser {
+		return T["@="]("from", B["@seq"](Open, S["@_"]("FROM"), B["@^"](Expr, Close)))()
+	})
+	Field = Rule("field", func() Parser {
+		return T["@="]("field", B["@seq"](Open, S["@_"]("."), B["@^"](NonParens, NonParens, Close)))()
+	})
+	Desc = Rule("desc", func() Parser {
+		return T["@="]("desc", B["@seq"](Open, S["@_"]("DESC"), B["@^"](Expr, Close)))()
+	})
+	Aes = Rule("aes", func() Parser {
+		return T["@="]("aes", B["@seq"](Open, S["@_"]("AES"), B["@^"](Expr, Close)))()
+	})
+	Order = Rule("order", func() Parser {
+		return T["@="]("order", B["@seq"](Open, S["@_"]("ORDER"), B["@^"](Expr, O["@+"](B["@or"](Desc, Aes)), Close)))()
+	})
+	Limit = Rule("limit", func() Parser {
+		return T["@="]("limit", B["@seq"](Open, S["@_"]("LIMIT"), B["@^"](Expr, Expr, Expr, Close)))()
+	})
+	Func = Rule("func", func() Parser {
+		return T["@="]("func", B["@seq"](Open,
+			B["@!"](B["@or"](
+				S["$$"]("SELECT"), S["$$"]("HAVING"), S["$$"]("GROUP"), S["$$"]("WHERE"),
+				S["$$"]("ON"), S["$$"]("JOIN"), S["$$"]("AS"), S["$$"]("FROM"), S["$$"]("."),
+				S["$$"]("DESC"), S["$$"]("AES"), S["$$"]("ORDER"), S["$$"]("LIMIT"),
+			)),
+			B["@*"](Expr),
+			Close))()
+	})
+
+	SetParameters()
+	t, _ := Eval(Select, Scan(s))
+	return t
+}
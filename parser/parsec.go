@@ -2,106 +2,275 @@ package parser
 
 import (
 	"encoding/json"
+	"fmt"
+	"math/big"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
 )
 
+// 扫描错误, Scan 以 panic 的形式抛出, ScanErr 将其作为 error 返回
+type ScanError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ScanError) Error() string {
+	return strconv.Itoa(e.Pos) + ": " + e.Msg
+}
+
+// 返回 s[start] 处字符串字面量引号之间的原文
 func ScanString(s string, start int) string {
-	quotationMark := s[start : start+1]
-	var loop func(int, bool, string) string
-	loop = func(start int, skip bool, str string) string {
-		curChar := s[start : start+1]
-		if start == len(s) {
-			return ""
-		} else if skip {
-			return loop(1+start, false, str+curChar)
-		} else if curChar == "\\" {
-			return loop(1+start, true, str+"\\")
-		} else if curChar == quotationMark {
-			return str
+	text, _, _ := scanString(s, start, StartWithOneOf(s, start, quotation_marks), false)
+	return text
+}
+
+// 扫描以 quote 开头的字符串字面量, 返回引号之间的原文, 解码后的值和结束位置.
+// raw 为 true 时不处理转义; doubled_quote_escape 时连续两个引号表示一个引号.
+func scanString(s string, start int, quote string, raw bool) (text, value string, end int) {
+	if quote == "" {
+		quote = s[start : start+1]
+	}
+	var b strings.Builder
+	pos := start + len(quote)
+	for {
+		if pos >= len(s) {
+			panic(&ScanError{Pos: start, Msg: "unterminated string"})
+		}
+		if StartWith(s, pos, quote) != "" {
+			if doubled_quote_escape && StartWith(s, pos+len(quote), quote) != "" {
+				b.WriteString(quote)
+				pos += 2 * len(quote)
+				continue
+			}
+			return s[start+len(quote) : pos], b.String(), pos + len(quote)
+		}
+		if raw || s[pos] != '\\' {
+			r, size := utf8.DecodeRuneInString(s[pos:])
+			b.WriteRune(r)
+			pos += size
+			continue
+		}
+		// strconv.UnquoteChar 只认单字节的引号
+		q := byte(0)
+		if len(quote) == 1 {
+			q = quote[0]
+		}
+		r, _, tail, err := strconv.UnquoteChar(s[pos:], q)
+		if err != nil {
+			if pos+1 >= len(s) {
+				panic(&ScanError{Pos: start, Msg: "unterminated string"})
+			}
+			panic(&ScanError{Pos: pos, Msg: "invalid escape " + strconv.Quote(s[pos:pos+2])})
 		}
-		return loop(1+start, false, str+curChar)
+		b.WriteRune(r)
+		pos = len(s) - len(tail)
 	}
-	return loop(1+start, false, "")
 }
 
 func Scan(s string) []*Node {
-	var scan1 func(string, int) (*Node, int)
-	scan1 = func(s string, start int) (*Node, int) {
-		if start == len(s) {
-			return &Node{Type: EofType}, start
+	toks := make([]*Node, 0)
+	lay := newLayout()
+	for start := 0; ; {
+		tok, end := scanToken(s, start)
+		if indent_mode {
+			toks = append(toks, lay.before(s, start, tok)...)
 		}
-		if StartWithOneOf(s, start, significant_whitespaces) != "" {
-			return NewNode(NewlineType, start, start+1, nil, "", 0, nil), start + 1
+		if tok.Type == EofType {
+			return toks
 		}
-		if IsWhitespace(s[start : start+1]) {
-			return scan1(s, 1+start)
+		toks, start = append(toks, tok), end
+	}
+}
+
+// 扫描 s[start:] 中的下一个 token, 返回 token 和下一次扫描的起始位置, 输入结束时返回 eof 节点
+func scanToken(s string, start int) (*Node, int) {
+	for start < len(s) && !significantAt(s, start) {
+		r, size := utf8.DecodeRuneInString(s[start:])
+		if !unicode.IsSpace(r) {
+			break
 		}
-		if StartWithOneOf(s, start, line_comment) != "" {
-			lineEnd := FindNext(s, start, func(s string, start int) bool {
-				return s[start:start+1] == "\n"
-			})
-			return NewNode(CommentType, start, 1+lineEnd, nil, s[start:lineEnd], 0, nil), lineEnd
+		start += size
+	}
+	if start == len(s) {
+		return &Node{Type: EofType, Start: start, End: start}, start
+	}
+	if significantAt(s, start) {
+		return NewNode(NewlineType, start, start+1, nil, "", 0, nil), start + 1
+	}
+	if StartWithOneOf(s, start, line_comment) != "" {
+		lineEnd := FindNext(s, start, func(s string, start int) bool {
+			return s[start:start+1] == "\n"
+		})
+		end := 1 + lineEnd
+		if lineEnd == -1 {
+			lineEnd, end = len(s), len(s)
 		}
-		if StartWith(s, start, comment_start) != "" {
-			lineEnd := FindNext(s, start, func(s string, start int) bool {
-				return StartWith(s, start, comment_end) != ""
-			})
-			end := lineEnd + len(comment_end)
-			return NewNode(CommentType, start, end, nil, s[start:end], 0, nil), end
+		return NewNode(CommentType, start, end, nil, s[start:lineEnd], 0, nil), lineEnd
+	}
+	if pair, ok := blockComment(s, start); ok {
+		end := scanBlockComment(s, start, pair)
+		return NewNode(CommentType, start, end, nil, s[start:end], 0, nil), end
+	}
+	if tok, end := scanTokenRule(s, start); tok != nil {
+		return tok, end
+	}
+	// 引号可能同时是分隔符, 如 SetParameters 中的 `, 因此先于分隔符判断
+	if quote := StartWithOneOf(s, start, raw_quotation_marks); quote != "" {
+		text, value, end := scanString(s, start, quote, true)
+		str := NewNode(StrType, start, end, nil, text, 0, nil)
+		str.Value = value
+		return str, end
+	}
+	if quote := StartWithOneOf(s, start, quotation_marks); quote != "" {
+		text, value, end := scanString(s, start, quote, false)
+		str := NewNode(StrType, start, end, nil, text, 0, nil)
+		str.Value = value
+		return str, end
+	}
+	if num, end := scanNumber(s, start); num != nil {
+		return num, end
+	}
+	if delim := FindDelim(s, start); delim != "" {
+		end := start + len(delim)
+		return NewNode(TokenType, start, end, nil, delim, 0, nil), end
+	}
+	if op := FindOperator(s, start); op != "" {
+		end := start + len(op)
+		return NewNode(TokenType, start, end, nil, op, 0, nil), end
+	}
+	if prefix := StartWithOneOf(s, start, lisp_char); prefix != "" {
+		pos := start + len(prefix)
+		if len(s) <= pos {
+			panic(&ScanError{Pos: start, Msg: "reached EOF while scanning char"})
 		}
-		if delim := FindDelim(s, start); delim != "" {
-			end := start + len(delim)
-			return NewNode(TokenType, start, end, nil, delim, 0, nil), end
+		_, size := utf8.DecodeRuneInString(s[pos:])
+		end := pos + size
+		for end < len(s) {
+			r, size := utf8.DecodeRuneInString(s[end:])
+			if unicode.IsSpace(r) || FindDelim(s, end) != "" {
+				break
+			}
+			end += size
 		}
-		if op := FindOperator(s, start); op != "" {
-			end := start + len(op)
-			return NewNode(TokenType, start, end, nil, op, 0, nil), end
+		return NewNode(CharacterType, start, end, nil, s[pos:end], 0, nil), end
+	}
+	pos := start
+	for pos < len(s) && FindDelim(s, pos) == "" && FindOperator(s, pos) == "" {
+		r, size := utf8.DecodeRuneInString(s[pos:])
+		if unicode.IsSpace(r) {
+			break
 		}
-		if StartWithOneOf(s, start, quotation_marks) != "" {
-			str := ScanString(s, start)
-			end := start + len(str) + 2
-			return NewNode(StrType, start, end, nil, str, 0, nil), end
+		pos += size
+	}
+	return NewNode(TokenType, start, pos, nil, s[start:pos], 0, nil), pos
+}
+
+// s[start] 处开始的块注释, 有多个时取开始符号最长的
+func blockComment(s string, start int) (pair [2]string, ok bool) {
+	for _, p := range block_comments {
+		if len(p[0]) > len(pair[0]) && StartWith(s, start, p[0]) != "" {
+			pair, ok = p, true
 		}
-		if StartWithOneOf(s, start, lisp_char) != "" {
-			if len(s) <= 2+start {
-				panic("scan-string: reached EOF while scanning char")
-			}
-			var loop func(int) int
-			loop = func(end int) int {
-				if IsWhitespace(s[end:end+1]) || IsDelim(s[end:end+1]) {
-					return end
-				} else {
-					return loop(end + 1)
-				}
-			}
-			end := loop(3 + start)
-			return NewNode(CharacterType, start, end, nil, s[end-1:end], 0, nil), end
-		}
-		var loop func(int, string) (*Node, int)
-		loop = func(pos int, chars string) (*Node, int) {
-			if len(s) <= pos ||
-				IsWhitespace(s[pos:pos+1]) ||
-				FindDelim(s, pos) != "" ||
-				FindOperator(s, pos) != "" {
-				return NewNode(TokenType, start, pos, nil, chars, 0, nil), pos
-			} else {
-				return loop(1+pos, chars+s[pos:pos+1])
-			}
+	}
+	return pair, ok
+}
+
+// 返回块注释的结束位置, 开启 nested_comments 时同一种注释可以嵌套
+func scanBlockComment(s string, start int, pair [2]string) int {
+	open, close := pair[0], pair[1]
+	depth, pos := 1, start+len(open)
+	for depth > 0 {
+		switch {
+		case pos >= len(s):
+			panic(&ScanError{Pos: start, Msg: "unterminated comment"})
+		case StartWith(s, pos, close) != "":
+			depth, pos = depth-1, pos+len(close)
+		case nested_comments && StartWith(s, pos, open) != "":
+			depth, pos = depth+1, pos+len(open)
+		default:
+			pos++
 		}
-		return loop(start, "")
 	}
-	var loop func(int, []*Node) []*Node
-	loop = func(start int, toks []*Node) []*Node {
-		tok, newStart := scan1(s, start)
-		if tok.Type == EofType {
-			return toks
-		} else {
-			return loop(newStart, append(toks, tok))
+	return pos
+}
+
+// 扫描 s[start] 处的数字字面量, 不是数字时返回 nil.
+// 支持十进制, 0x 十六进制, 0o/0 八进制, 0b 二进制, 小数, 指数和 `_` 分隔,
+// Value 为 int64 或 float64, 超出范围时为 *big.Int 或 *big.Float.
+// 形如 1.2.3, 12abc, 08 的不是数字, 仍按普通 token 扫描.
+// 数字 token 的 Text 为原文, 仍可以用 $$ 按文本匹配.
+func scanNumber(s string, start int) (*Node, int) {
+	isDigit := func(pos int) bool { return pos < len(s) && '0' <= s[pos] && s[pos] <= '9' }
+	if !isDigit(start) && !(s[start] == '.' && isDigit(start+1)) {
+		return nil, start
+	}
+	exp := "eE"
+	if StartWith(s, start, "0x") != "" || StartWith(s, start, "0X") != "" {
+		exp = "pP"
+	}
+	end := start
+loop:
+	for end < len(s) {
+		switch c := s[end]; {
+		case c == '_' || c == '.' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z':
+			end++
+		case (c == '+' || c == '-') && strings.IndexByte(exp, s[end-1]) >= 0:
+			end++
+		default:
+			break loop
 		}
 	}
-	return loop(0, make([]*Node, 0))
+	if r, _ := utf8.DecodeRuneInString(s[end:]); end < len(s) && id_part(r) {
+		return nil, start
+	}
+	// 0 开头的整数是八进制, 不能按小数解析成 8.0, 9.0
+	if text := s[start:end]; len(text) > 1 && text[0] == '0' && strings.Trim(text, "0123456789_") == "" && strings.ContainsAny(text, "89") {
+		return nil, start
+	}
+	value := parseNumber(s[start:end])
+	if value == nil {
+		return nil, start
+	}
+	num := NewNode(NumberType, start, end, nil, s[start:end], 0, nil)
+	num.Value = value
+	return num, end
+}
+
+func parseNumber(text string) interface{} {
+	if i, err := strconv.ParseInt(text, 0, 64); err == nil {
+		return i
+	} else if err.(*strconv.NumError).Err == strconv.ErrRange {
+		if b, ok := new(big.Int).SetString(text, 0); ok {
+			return b
+		}
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return f
+	} else if err.(*strconv.NumError).Err == strconv.ErrRange {
+		if b, _, err := big.ParseFloat(strings.Replace(text, "_", "", -1), 0, 256, big.ToNearestEven); err == nil {
+			return b
+		}
+	}
+	return nil
+}
+
+// 同 Scan, 扫描错误作为 error 返回
+func ScanErr(s string) (toks []*Node, err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			e, ok := x.(*ScanError)
+			if !ok {
+				panic(x)
+			}
+			toks, err = nil, e
+		}
+	}()
+	return Scan(s), nil
 }
 
 type Pair struct {
@@ -147,8 +316,9 @@ func Ext(u Combinator, v []*Node, stk []*Pair) []*Pair {
 type Parser func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node)
 type Combinator func() Parser
 
+// 失败时撤销状态的修改
 func ApplyCheck(combinator Combinator, toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
-	return combinator()(toks, Ext(combinator, toks, stk), ctx)
+	return rollback(combinator(), toks, Ext(combinator, toks, stk), ctx)
 }
 
 // @seq
@@ -166,7 +336,9 @@ func AtSeq(cs ...Combinator) Combinator {
 					return loop(cs[1:], r, append(nodes, t...))
 				}
 			}
-			return loop(cs, toks, make([]*Node, 0))
+			return rollback(func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+				return loop(cs, toks, make([]*Node, 0))
+			}, toks, stk, ctx)
 		}
 	}
 }
@@ -268,7 +440,7 @@ func AtStar_(c Combinator) Combinator {
 				if len(toks) == 0 {
 					return nodes, make([]*Node, 0)
 				}
-				if t, r := c()(toks, stk, ctx); t == nil {
+				if t, r := ApplyCheck(c, toks, stk, ctx); t == nil {
 					return nodes, toks
 				} else {
 					return loop(r, append(nodes, t...))
@@ -294,7 +466,11 @@ func AtFail(cs ...Combinator) Combinator {
 	parser := AtDot(cs...)()
 	return func() Parser {
 		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
-			if t, _ := parser(toks, stk, ctx); t == nil {
+			if len(toks) == 0 {
+				return nil, nil
+			}
+			if t, _ := lookahead(parser, toks, stk, ctx); t == nil {
+				reachMore(toks, ctx)
 				return []*Node{toks[0]}, toks[1:]
 			} else {
 				return nil, nil
@@ -307,7 +483,11 @@ func AtFail(cs ...Combinator) Combinator {
 func AtFail_(c Combinator) Combinator {
 	return func() Parser {
 		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
-			if t, _ := c()(toks, stk, ctx); t == nil {
+			if len(toks) == 0 {
+				return nil, nil
+			}
+			if t, _ := lookahead(c(), toks, Ext(c, toks, stk), ctx); t == nil {
+				reachMore(toks, ctx)
 				return []*Node{toks[0]}, toks[1:]
 			} else {
 				return nil, nil
@@ -325,7 +505,14 @@ func AtAnd(cs ...Combinator) Combinator {
 				if len(cs) == 0 {
 					return res[0], res[1]
 				}
-				if t, r := ApplyCheck(cs[0], toks, stk, ctx); t == nil {
+				// 最后一个以外的只是向前看
+				apply := ApplyCheck
+				if len(cs) > 1 {
+					apply = func(c Combinator, toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+						return lookahead(c(), toks, Ext(c, toks, stk), ctx)
+					}
+				}
+				if t, r := apply(cs[0], toks, stk, ctx); t == nil {
 					return nil, nil
 				} else {
 					return loop(cs[1:], append([][]*Node{t, r}, res...))
@@ -356,7 +543,7 @@ func _glob(cs ...Combinator) Combinator {
 func _glob_(c Combinator) Combinator {
 	return func() Parser {
 		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
-			if t, r := c()(toks, stk, ctx); t == nil {
+			if t, r := ApplyCheck(c, toks, stk, ctx); t == nil {
 				return nil, nil
 			} else {
 				return make([]*Node, 0), r
@@ -406,6 +593,7 @@ func _none() Parser {
 func _pred(proc func(*Node) bool) Combinator {
 	return func() Parser {
 		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			reachMore(toks, ctx)
 			if len(toks) == 0 {
 				return nil, nil
 			} else if proc(toks[0]) {
@@ -424,9 +612,18 @@ var _eof = _glob(_pred(func(t *Node) bool {
 
 // $$
 func __(s string) Combinator {
-	return _pred(func(x *Node) bool {
-		return IsTokenType(x) && x.Text == s
+	tok := _pred(func(x *Node) bool {
+		return (IsTokenType(x) || IsNumber(x)) && x.Text == s
 	})
+	chars := charLiteral(s)
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			if len(toks) > 0 && IsRune(toks[0]) {
+				return chars()(toks, stk, ctx)
+			}
+			return tok()(toks, stk, ctx)
+		}
+	}
 }
 
 // @_
@@ -455,30 +652,34 @@ func AtDotAt(c, sep Combinator) Combinator {
 // 后缀表达式
 // @postfix
 func AtPostfix(tp string, c, op Combinator) Combinator {
+	return AtPostfixWith(TripleBuilder, tp, c, op)
+}
+
+// 同 @postfix, 用 b 构造节点
+func AtPostfixWith(b Builder, tp string, c, op Combinator) Combinator {
 	return func() Parser {
 		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
 			if t, r := ApplyCheck(AtDot(c, AtAdd(op)), toks, stk, ctx); t == nil {
 				return nil, nil
 			} else {
-				return []*Node{MakePostfix(tp, t)}, r
+				return []*Node{BuildPostfix(b, tp, t)}, r
 			}
 		}
 	}
 }
 
 func MakePostfix(tp string, ls []*Node) *Node {
+	return BuildPostfix(TripleBuilder, tp, ls)
+}
+
+// ls 为 操作数 操作符 操作符 ...
+func BuildPostfix(b Builder, tp string, ls []*Node) *Node {
 	var loop func([]*Node, *Node) *Node
 	loop = func(ls []*Node, ret *Node) *Node {
 		if len(ls) == 0 {
 			return ret
 		} else {
-			e := &Node{
-				Type:  tp,
-				Start: ret.Start,
-				End:   ls[0].End,
-				Elts:  []*Node{ret, ls[0]},
-			}
-			return loop(ls[1:], e)
+			return loop(ls[1:], b("postfix", tp, ls[0], []*Node{ret}))
 		}
 	}
 	return loop(ls[1:], ls[0])
@@ -487,70 +688,85 @@ func MakePostfix(tp string, ls []*Node) *Node {
 // 前缀表达式
 // @prefix
 func AtPrefix(tp string, c, op Combinator) Combinator {
+	return AtPrefixWith(TripleBuilder, tp, c, op)
+}
+
+// 同 @prefix, 用 b 构造节点
+func AtPrefixWith(b Builder, tp string, c, op Combinator) Combinator {
 	return func() Parser {
 		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
 			if t, r := ApplyCheck(AtDot(AtAdd(op), c), toks, stk, ctx); t == nil {
 				return nil, nil
 			} else {
-				return []*Node{MakePrefix(tp, t)}, r
+				return []*Node{BuildPrefix(b, tp, t)}, r
 			}
 		}
 	}
 }
 
 func MakePrefix(tp string, ls []*Node) *Node {
+	return BuildPrefix(TripleBuilder, tp, ls)
+}
+
+// ls 为 操作符 ... 操作符 操作数
+func BuildPrefix(b Builder, tp string, ls []*Node) *Node {
 	if len(ls) == 1 {
 		return ls[0]
 	} else {
-		tail := MakePrefix(tp, ls[1:])
-		return &Node{
-			Type:  tp,
-			Start: ls[0].Start,
-			End:   tail.End,
-			Elts:  []*Node{ls[0], tail},
-		}
+		return b("prefix", tp, ls[0], []*Node{BuildPrefix(b, tp, ls[1:])})
 	}
 }
 
 // @infix
 func AtInfix(tp string, c, op Combinator, associativity string) Combinator {
+	return AtInfixWith(TripleBuilder, tp, c, op, associativity)
+}
+
+// 同 @infix, 用 b 构造节点
+func AtInfixWith(b Builder, tp string, c, op Combinator, associativity string) Combinator {
 	return func() Parser {
 		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
-			var loop func([]*Node, []*Node) ([]*Node, []*Node)
-			loop = func(rest []*Node, ret []*Node) ([]*Node, []*Node) {
+			// s 为解析最后一个运算符之前的状态, 丢弃该运算符时恢复
+			var loop func([]*Node, []*Node, snapshot) ([]*Node, []*Node)
+			loop = func(rest []*Node, ret []*Node, s snapshot) ([]*Node, []*Node) {
 				if tc, rc := ApplyCheck(AtSeq(c), rest, stk, ctx); tc == nil {
 					if lc := len(ret); lc < 3 {
 						return nil, nil
 					} else {
-						fields := ret[:lc-1]
-						constr := constrExpL
-						if associativity == "right" {
-							constr = constrExpR
-						}
-						return []*Node{constr(tp, fields)}, append([]*Node{ret[lc-1]}, rest...)
+						reset(ctx, s)
+						return []*Node{makeInfix(b, tp, ret[:lc-1], associativity)}, append([]*Node{ret[lc-1]}, rest...)
 					}
 				} else {
+					before := mark(ctx)
 					if top, rop := ApplyCheck(AtSeq(op), rc, stk, ctx); top == nil {
 						if lc := len(ret); lc < 2 {
 							return nil, nil
 						} else {
-							fields := append(ret, tc...)
-							constr := constrExpL
-							if associativity == "right" {
-								constr = constrExpR
-							}
-							return []*Node{constr(tp, fields)}, rc
+							return []*Node{makeInfix(b, tp, append(ret, tc...), associativity)}, rc
 						}
 					} else {
-						return loop(rop, append(ret, append(tc, top...)...))
+						return loop(rop, append(ret, append(tc, top...)...), before)
 					}
 				}
 			}
-			return loop(toks, make([]*Node, 0))
+			return loop(toks, make([]*Node, 0), mark(ctx))
 		}
 	}
 }
 
+// 不结合时只允许一个运算符, 如 a < b < c 抛出 ParseError
+func makeInfix(b Builder, tp string, fields []*Node, associativity string) *Node {
+	if associativity == "none" && len(fields) > 3 {
+		panic(NonAssociative(fields[3]))
+	}
+	return BuildInfix(b, tp, fields, associativity)
+}
+
+// 不结合的运算符 op 连用时的错误, 生成的解析器也使用
+func NonAssociative(op *Node) *ParseError {
+	return &ParseError{Pos: op.Start, Msg: fmt.Sprintf("non-associative operator %q cannot be chained", op.Text)}
+}
+
 // @infix-left
 func AtInfixLeft(tp string, c, op Combinator) Combinator {
 	return AtInfix(tp, c, op, "left")
@@ -561,82 +777,132 @@ func AtInfixRight(tp string, c, op Combinator) Combinator {
 	return AtInfix(tp, c, op, "right")
 }
 
-func constrExpL(tp string, fields []*Node) *Node {
+// @infix-none
+func AtInfixNone(tp string, c, op Combinator) Combinator {
+	return AtInfix(tp, c, op, "none")
+}
+
+// fields 为 操作数 操作符 操作数 ... 交替排列
+func MakeInfix(tp string, fields []*Node, associativity string) *Node {
+	return BuildInfix(TripleBuilder, tp, fields, associativity)
+}
+
+func BuildInfix(b Builder, tp string, fields []*Node, associativity string) *Node {
+	if associativity == "right" {
+		return constrExpR(b, tp, fields)
+	}
+	return constrExpL(b, tp, fields)
+}
+
+func constrExpL(b Builder, tp string, fields []*Node) *Node {
 	var loop func([]*Node, *Node) *Node
 	loop = func(fields []*Node, ret *Node) *Node {
 		if len(fields) == 0 {
 			return ret
 		} else {
-			e := &Node{
-				Type:  tp,
-				Start: ret.Start,
-				End:   fields[1].End,
-				Elts:  []*Node{ret, fields[0], fields[1]},
-			}
-			return loop(fields[2:], e)
+			return loop(fields[2:], b("infix", tp, fields[0], []*Node{ret, fields[1]}))
 		}
 	}
 	return loop(fields[1:], fields[0])
 }
 
-func constrExpR(tp string, fields []*Node) *Node {
+func constrExpR(b Builder, tp string, fields []*Node) *Node {
 	fields = reverse(fields)
 	var loop func([]*Node, *Node) *Node
 	loop = func(fields []*Node, ret *Node) *Node {
 		if len(fields) == 0 {
 			return ret
 		} else {
-			e := &Node{
-				Type:  tp,
-				Start: fields[1].Start,
-				End:   ret.End,
-				Elts:  []*Node{fields[1], fields[0], ret},
-			}
-			return loop(fields[2:], e)
+			return loop(fields[2:], b("infix", tp, fields[0], []*Node{fields[1], ret}))
 		}
 	}
 	return loop(fields[1:], fields[0])
 }
 
 // :: 加一层缓存
+// 设置了 Tracer 时, 以函数名作为规则名记录进出
 func CC(c Combinator) Combinator {
 	return func() Parser {
 		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
-			if cache, ok := ctx.(map[string][][]*Node); !ok {
-				return c()(toks, stk, ctx)
-			} else if t, r := getCache(cache, c, toks); t != nil {
+			if tracer == nil {
+				return cc(c, toks, stk, ctx)
+			}
+			return traceRule(functionName(c, '.'), toks, func() ([]*Node, []*Node) {
+				return cc(c, toks, stk, ctx)
+			})
+		}
+	}
+}
+
+// Rule 的编号, 缓存按编号区分, 同名的不同规则不会共用缓存
+var rule_count int64
+
+// 具名规则: 跟踪, 缓存, 错误信息都使用 name 而不是函数名
+func Rule(name string, c Combinator) Combinator {
+	id := strconv.FormatInt(atomic.AddInt64(&rule_count, 1), 10)
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			st, hasState := ctx.(*ParseState)
+			parse := func() ([]*Node, []*Node) {
+				if !hasState {
+					return c()(toks, stk, ctx)
+				}
+				// toks 总是输入的后缀, 长度即可确定位置
+				key := id + "@" + strconv.Itoa(len(toks))
+				if e, ok := st.lookup(key); ok {
+					return e.t, e.r
+				}
+				// 抛出 ParseError 时不出栈, 由接住它的 try 取栈顶的规则名
+				st.rules = append(st.rules, name)
+				from := st.save()
+				t, r := c()(toks, stk, ctx)
+				st.store(key, from, t, r)
+				st.rules = st.rules[:len(st.rules)-1]
 				return t, r
-			} else {
-				nt, nr := c()(toks, stk, ctx)
-				setCache(cache, c, toks, nt, nr)
-				return nt, nr
 			}
+			if tracer == nil {
+				return parse()
+			}
+			return traceRule(name, toks, parse)
 		}
 	}
 }
 
-func getCache(cache map[string][][]*Node, c Combinator, toks []*Node) ([]*Node, []*Node) {
+func cc(c Combinator, toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+	if st, ok := ctx.(*ParseState); !ok {
+		return c()(toks, stk, ctx)
+	} else if t, r := getCache(st, c, toks); t != nil {
+		return t, r
+	} else {
+		from := st.save()
+		nt, nr := c()(toks, stk, ctx)
+		setCache(st, from, c, toks, nt, nr)
+		return nt, nr
+	}
+}
+
+func getCache(st *ParseState, c Combinator, toks []*Node) ([]*Node, []*Node) {
 	key := getCacheKey(c, toks)
-	if res, ok := cache[key]; !ok {
+	if e, ok := st.lookup(key); !ok {
 		return nil, nil
 	} else {
-		return res[0], res[1]
+		return e.t, e.r
 	}
 }
 
-func setCache(cache map[string][][]*Node, c Combinator, toks, nt, nr []*Node) {
+func setCache(st *ParseState, from snapshot, c Combinator, toks, nt, nr []*Node) {
 	key := getCacheKey(c, toks)
-	cache[key] = [][]*Node{nt, nr}
+	st.store(key, from, nt, nr)
 }
 
 func getCacheKey(c Combinator, toks []*Node) string {
 	name := functionName(c)
 	runes := make([]rune, 0, len(toks)*100)
 	for _, tok := range toks {
-		runes = append(runes, tok.Text...)
+		runes = append(runes, []rune(tok.Text)...)
 	}
 	res, _ := json.Marshal([]string{name, string(runes)})
-	return res
+	return string(res)
 }
 
 func functionName(i interface{}, seps ...rune) string {
@@ -659,9 +925,10 @@ func functionName(i interface{}, seps ...rune) string {
 	return ""
 }
 
+// 剪枝后的失败同样返回 nil, 需要错误信息时用 EvalErr
 func Eval(c Combinator, toks []*Node) ([]*Node, []*Node) {
-	cache := make(map[string][][]*Node, len(toks))
-	return c()(toks, make([]*Pair, 0), cache)
+	t, r, _ := EvalErr(c, toks)
+	return t, r
 }
 
 var (
@@ -673,39 +940,53 @@ var (
 		"@*":       AtStar,
 		"@...":     AtDot,
 		"@?":       AtWhy,
+		"@^":       AtCut,
 		"$glob":    _glob,
 		"$phantom": _phantom,
 	}
 	O = map[string]func(Combinator) Combinator{
-		"@+":     AtAdd,
-		"@*^":    AtStar_,
-		"@!^":    AtFail_,
-		"$glob^": _glob_,
-		"::":     CC,
+		"@+":               AtAdd,
+		"@*^":              AtStar_,
+		"@!^":              AtFail_,
+		"$glob^":           _glob_,
+		"::":               CC,
+		"@look-ahead":      LookAhead,
+		"@not-followed-by": NotFollowedBy,
 	}
 	T = map[string]func(string, ...Combinator) Combinator{
-		"@=": AtEq,
+		"@=":      AtEq,
+		"$lexeme": func(tp string, cs ...Combinator) Combinator { return Lexeme(tp, AtSeq(cs...)) },
 	}
 	S = map[string]func(string) Combinator{
-		"@~": AtSkip,
-		"$$": __,
-		"@_": At_,
+		"@~":     AtSkip,
+		"$$":     __,
+		"@_":     At_,
+		"$kind":  Kind,
+		"$str":   Str,
+		"$regex": Regex,
+		"$class": CharClass,
 	}
 	C = map[string]Combinator{
-		"$fail": _fail,
-		"$none": _none,
-		"$eof":  _eof,
+		"$fail":   _fail,
+		"$none":   _none,
+		"$eof":    _eof,
+		"$spaces": Spaces,
 	}
 	P = map[string]func(func(*Node) bool) Combinator{
-		"$pred": _pred,
+		"$pred": tokenPred,
 	}
 	J = map[string]func(c, sep Combinator) Combinator{
-		"@.@": AtDotAt,
+		"@.@":            AtDotAt,
+		"@recover-until": AtRecoverUntil,
+		"@sep-by":        SepBy,
+		"@sep-end-by":    SepEndBy,
+		"@many-till":     ManyTill,
 	}
 	F = map[string]func(tp string, c, op Combinator) Combinator{
 		"@prefix":      AtPrefix,
 		"@postfix":     AtPostfix,
 		"@infix-left":  AtInfixLeft,
 		"@infix-right": AtInfixRight,
+		"@infix-none":  AtInfixNone,
 	}
 )
@@ -0,0 +1,301 @@
+package parser
+
+import "fmt"
+
+// 模式匹配与改写规则
+// --------------------------------------------
+//
+// 规则文本由若干条 `pattern => replacement` 组成, 两边都是 sexp, 如
+//
+//	(= ?x ?x)       => true
+//	(and true ?y)   => ?y
+//	(and ?xs... false ?ys...) => false
+//
+// 模式的含义:
+//
+//	?x          匹配任意节点, 同名的变量要匹配结构相同的节点
+//	?_          匹配任意节点, 不绑定
+//	?x...       在列表中匹配零个或多个节点, 尽量少匹配
+//	atom        匹配 Text 相同的叶子节点, 字符串只匹配字符串
+//	(h p1 ...)  匹配 sexp 节点 (h p1 ...), 或者 Type 为 h, Elts 为 (p1 ...) 的节点
+//
+// 替换中的变量换成绑定的节点 (的副本). 匹配到的是 sexp 节点时, 替换中的列表生成 sexp 节点,
+// 否则生成以列表开头为 Type 的节点. 生成的节点的位置为被替换节点的位置.
+//
+// 其他解析器生成的树要按节点类型写模式, 如 ParseSQL 把 (= a a) 解析为 Type 为 func 的节点,
+// 对应的规则是 (func = ?x ?x) => true.
+//
+// ApplyRules 按后序反复改写整棵树, 直到没有规则改变树.
+// 同一个节点上按规则的顺序尝试, 使用第一条匹配的规则; 结果与原节点结构相同时不算改变.
+// 改写产生的节点总数超过 SetRewriteLimit 的上限时返回错误, 以免规则不终止或树无限增长.
+
+const patternArrow = "=>"
+
+type RewriteRule struct {
+	Pattern, Replacement *Node
+}
+
+// 变量名 (不含 ?) -> 绑定的节点, ?x 绑定一个节点, ?x... 绑定零个或多个
+type Bindings map[string][]*Node
+
+// 每次 ApplyRules 中改写产生的节点总数的上限
+var rewrite_limit = 100000
+
+func SetRewriteLimit(n int) {
+	rewrite_limit = n
+}
+
+// 读取规则文本, `//` 开头的是注释
+func ParseRewriteRules(src string) ([]*RewriteRule, error) {
+	nodes, err := parseGrammarSource(src)
+	if err != nil {
+		return nil, fmt.Errorf("rules: %v", err)
+	}
+	nodes = filter(negate(IsComment), nodes)
+	rules := make([]*RewriteRule, 0)
+	for len(nodes) > 0 {
+		if len(nodes) < 3 || !IsTokenType(nodes[1]) || nodes[1].Text != patternArrow {
+			return nil, fmt.Errorf("rules: %d: expected `pattern %s replacement`", nodes[0].Start, patternArrow)
+		}
+		r, err := NewRewriteRule(nodes[0], nodes[2])
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+		nodes = nodes[3:]
+	}
+	return rules, nil
+}
+
+// 检查变量的用法: ?x... 只出现在列表中且不在开头, 替换中的变量都在模式中出现
+func NewRewriteRule(pattern, replacement *Node) (*RewriteRule, error) {
+	vars := make(map[string]bool) // 变量名 -> 是否为 ?x...
+	if err := checkPattern(pattern, vars, false, true); err != nil {
+		return nil, err
+	}
+	if err := checkPattern(replacement, vars, false, false); err != nil {
+		return nil, err
+	}
+	return &RewriteRule{Pattern: pattern, Replacement: replacement}, nil
+}
+
+func checkPattern(p *Node, vars map[string]bool, inList, define bool) error {
+	if name, splice, ok := metaVar(p); ok {
+		switch {
+		case splice && !inList:
+			return fmt.Errorf("rules: %d: %s outside a list", p.Start, p.Text)
+		case name == "_" && !define:
+			return fmt.Errorf("rules: %d: ?_ in replacement", p.Start)
+		case define:
+			vars[name] = splice
+		default:
+			list, ok := vars[name]
+			if !ok {
+				return fmt.Errorf("rules: %d: unbound variable %s", p.Start, p.Text)
+			}
+			if list && !splice {
+				return fmt.Errorf("rules: %d: %s matches a list, use %s...", p.Start, p.Text, p.Text)
+			}
+		}
+		return nil
+	}
+	if p.Type != "sexp" {
+		return nil
+	}
+	for i, e := range filter(negate(IsComment), p.Elts) {
+		// 列表开头可能匹配节点类型, 只能是一个节点
+		if _, splice, ok := metaVar(e); ok && splice && i == 0 {
+			return fmt.Errorf("rules: %d: %s at the head of a list", e.Start, e.Text)
+		}
+		if err := checkPattern(e, vars, true, define); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ?x 返回 x, ?x... 返回 x 和 splice
+func metaVar(n *Node) (name string, splice, ok bool) {
+	if !IsTokenType(n) || len(n.Text) < 2 || n.Text[0] != '?' {
+		return "", false, false
+	}
+	name = n.Text[1:]
+	if len(name) > 3 && name[len(name)-3:] == "..." {
+		return name[:len(name)-3], true, true
+	}
+	return name, false, true
+}
+
+// 结构相同: Type, Text, Op 相同, 子节点 (不含注释) 逐个相同
+func EqualNode(a, b *Node) bool {
+	if a.Type != b.Type || a.Text != b.Text || a.Op != b.Op {
+		return false
+	}
+	ea, eb := filter(negate(IsComment), a.Elts), filter(negate(IsComment), b.Elts)
+	if len(ea) != len(eb) {
+		return false
+	}
+	for i := range ea {
+		if !EqualNode(ea[i], eb[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *RewriteRule) Match(n *Node) (Bindings, bool) {
+	b := make(Bindings)
+	if !matchPattern(r.Pattern, n, b) {
+		return nil, false
+	}
+	return b, true
+}
+
+func bind(b Bindings, name string, nodes []*Node) bool {
+	if name == "_" {
+		return true
+	}
+	old, ok := b[name]
+	if !ok {
+		b[name] = nodes
+		return true
+	}
+	if len(old) != len(nodes) {
+		return false
+	}
+	for i := range old {
+		if !EqualNode(old[i], nodes[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchPattern(p, n *Node, b Bindings) bool {
+	if name, _, ok := metaVar(p); ok {
+		return bind(b, name, []*Node{n})
+	}
+	if p.Type != "sexp" {
+		return len(n.Elts) == 0 && p.Text == n.Text && IsStrType(p) == IsStrType(n)
+	}
+	pats, elts := filter(negate(IsComment), p.Elts), filter(negate(IsComment), n.Elts)
+	if n.Type != "sexp" {
+		// 列表开头匹配节点类型
+		if len(pats) == 0 || !matchPattern(pats[0], &Node{Type: TokenType, Text: n.Type}, b) {
+			return false
+		}
+		pats = pats[1:]
+	}
+	return matchList(pats, elts, b)
+}
+
+// ?x... 从短到长尝试
+func matchList(pats, elts []*Node, b Bindings) bool {
+	if len(pats) == 0 {
+		return len(elts) == 0
+	}
+	name, splice, ok := metaVar(pats[0])
+	if !ok || !splice {
+		return len(elts) > 0 && matchPattern(pats[0], elts[0], b) && matchList(pats[1:], elts[1:], b)
+	}
+	for k := 0; k <= len(elts); k++ {
+		try := make(Bindings, len(b))
+		for v, ns := range b {
+			try[v] = ns
+		}
+		if bind(try, name, elts[:k]) && matchList(pats[1:], elts[k:], try) {
+			for v, ns := range try {
+				b[v] = ns
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// 深拷贝
+func cloneNode(n *Node) *Node {
+	c := *n
+	if n.Elts != nil {
+		c.Elts = make([]*Node, len(n.Elts))
+		for i, e := range n.Elts {
+			c.Elts[i] = cloneNode(e)
+		}
+	}
+	return &c
+}
+
+// 用 b 实例化替换, sexp 为真时列表生成 sexp 节点
+func instantiate(p *Node, b Bindings, at *Node, sexp bool) []*Node {
+	if name, _, ok := metaVar(p); ok {
+		nodes := make([]*Node, len(b[name]))
+		for i, e := range b[name] {
+			nodes[i] = cloneNode(e)
+		}
+		return nodes
+	}
+	if p.Type != "sexp" {
+		n := cloneNode(p)
+		n.Start, n.End = at.Start, at.End
+		return []*Node{n}
+	}
+	pats := filter(negate(IsComment), p.Elts)
+	elts := make([]*Node, 0, len(pats))
+	for _, e := range pats {
+		elts = append(elts, instantiate(e, b, at, sexp)...)
+	}
+	n := &Node{Type: "sexp", Start: at.Start, End: at.End, Elts: elts}
+	if !sexp && len(elts) > 0 {
+		n.Type, n.Elts = elts[0].Text, elts[1:]
+	}
+	return []*Node{n}
+}
+
+// 如果 n 匹配模式, 返回替换后的节点
+func (r *RewriteRule) Apply(n *Node) (*Node, bool) {
+	b, ok := r.Match(n)
+	if !ok {
+		return nil, false
+	}
+	return instantiate(r.Replacement, b, n, n.Type == "sexp")[0], true
+}
+
+func countNodes(n *Node) int {
+	count := 0
+	Inspect(n, func(e *Node) bool {
+		if e != nil {
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// 反复改写 root 直到没有规则改变树, 返回改写后的树, root 被原地修改
+func ApplyRules(root *Node, rules []*RewriteRule) (*Node, error) {
+	budget := rewrite_limit
+	for {
+		changed := false
+		root = Rewrite(root, nil, func(c *Cursor) bool {
+			for _, r := range rules {
+				n, ok := r.Apply(c.Node())
+				if !ok {
+					continue
+				}
+				if !EqualNode(c.Node(), n) {
+					c.Replace(n)
+					changed = true
+					budget -= countNodes(n)
+				}
+				break
+			}
+			return budget >= 0
+		})
+		if budget < 0 {
+			return root, fmt.Errorf("rules: more than %d nodes rewritten, rules may not terminate", rewrite_limit)
+		}
+		if !changed {
+			return root, nil
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+// 文档中的规则
+const docRules = `
+(= ?x ?x)       => true
+(and true ?y)   => ?y
+(and ?xs... false ?ys...) => false
+`
+
+func rewriteDump(t *testing.T, rules []*RewriteRule, src string) string {
+	SetParameters()
+	got, err := ApplyRules(ParseSexp(src)[0], rules)
+	if err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	return dump(got)
+}
+
+func TestApplyRules(t *testing.T) {
+	rules, err := ParseRewriteRules(docRules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct{ src, want string }{
+		{"(= a a)", "token:true"},
+		{"(= a b)", "(sexp token:= token:a token:b)"},
+		{"(= (f a) (f a))", "token:true"},
+		{"(and true (= x x))", "token:true"},
+		{"(and true (or a b))", "(sexp token:or token:a token:b)"},
+		{"(or (and a false b) c)", "(sexp token:or token:false token:c)"},
+		{"(and false)", "token:false"},
+		// 改写子节点后父节点才能匹配
+		{"(and (= a a) (= b b))", "token:true"},
+	} {
+		if got := rewriteDump(t, rules, c.src); got != c.want {
+			t.Errorf("%q: got %s, want %s", c.src, got, c.want)
+		}
+	}
+}
+
+// 列表开头匹配节点类型
+func TestRuleNodeType(t *testing.T) {
+	SetParameters()
+	rules, err := ParseRewriteRules(`(additive ?x ?op 0) => ?x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero := &Node{Type: NumberType, Text: "0"}
+	root := &Node{Type: "additive", Elts: []*Node{{Type: TokenType, Text: "a"}, {Type: TokenType, Text: "+"}, zero}}
+	if got, err := ApplyRules(root, rules); err != nil || dump(got) != "token:a" {
+		t.Errorf("got %s %v", dump(got), err)
+	}
+}
+
+// ParseSQL 的树中运算符是 func 节点的第一个子节点
+func TestRuleSQL(t *testing.T) {
+	SetParameters()
+	rules, err := ParseRewriteRules(`(func = ?x ?x) => true`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ApplyRules(ParseSQL("(SELECT (WHERE (FROM t) (= a a)) x)")[0], rules)
+	if err != nil || dump(got) != "(select (where (from token:t) token:true) token:x)" {
+		t.Errorf("got %s %v", dump(got), err)
+	}
+}
+
+// 结果与原节点相同时不算改变, 不会一直改写下去
+func TestApplyRulesNoChange(t *testing.T) {
+	rules, err := ParseRewriteRules(`(f ?x) => (f ?x)  (g ?x) => ?x`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rewriteDump(t, rules, "(f (g a))"); got != "(sexp token:f token:a)" {
+		t.Errorf("got %s", got)
+	}
+}
+
+// 不终止或使树无限增长的规则在达到上限时报错
+func TestApplyRulesLimit(t *testing.T) {
+	SetParameters()
+	defer SetRewriteLimit(100000)
+	SetRewriteLimit(1000)
+	for _, c := range []struct{ rules, src string }{
+		{`(f ?x ?y) => (f ?y ?x)`, "(f a b)"},
+		{`(f ?x) => (f (f ?x))`, "(f a)"},
+	} {
+		rules, err := ParseRewriteRules(c.rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ApplyRules(ParseSexp(c.src)[0], rules); err == nil || !strings.Contains(err.Error(), "more than 1000 nodes") {
+			t.Errorf("%s: error = %v", c.rules, err)
+		}
+	}
+}
+
+func TestRuleErrors(t *testing.T) {
+	SetParameters()
+	for _, c := range []struct{ src, want string }{
+		{`(f ?x) => ?y`, "unbound variable ?y"},
+		{`(f ?xs...) => (g ?xs)`, "?xs matches a list, use ?xs..."},
+		{`?xs... => a`, "?xs... outside a list"},
+		{`(f ?x) => (g ?_)`, "?_ in replacement"},
+		{`(?xs... a) => a`, "?xs... at the head of a list"},
+		{`(f ?xs...) => (?xs... a)`, "?xs... at the head of a list"},
+		{`(f ?x) (g ?x)`, "expected `pattern => replacement`"},
+		{`(f ?x) => ?x ) (g ?x) => ?x`, `13: unexpected ")"`},
+	} {
+		if _, err := ParseRewriteRules(c.src); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: error = %v, want %s", c.src, err, c.want)
+		}
+	}
+	// 空文本和只有注释的文本没有规则
+	for _, src := range []string{"", " \n", "// x"} {
+		if rules, err := ParseRewriteRules(src); err != nil || len(rules) != 0 {
+			t.Errorf("%q: got %d rules, %v", src, len(rules), err)
+		}
+	}
+}
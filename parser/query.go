@@ -0,0 +1,485 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 语法树选择器
+// --------------------------------------------
+//
+// 用类似 CSS 的选择器查找节点:
+//
+//	field                   类型为 field 的节点, * 为任意类型
+//	where field             where 之内 (任意深度) 的 field
+//	where > field           where 的直接子节点 field
+//	[text=a]                属性: text, type, op; 运算符 = != ^= $= *= ~= (正则),
+//	                        [text] 表示 text 不为空; 值是带引号的字符串或到 ] 为止的原文
+//	:first :last :nth(n)    在父节点 Elts 中的位置, n 从 1 开始, 负数从末尾数起
+//	:empty                  没有子节点
+//	:has(sel)               有匹配 sel 的后代, sel 以 > 开头时为直接子节点
+//	:not(sel)               不匹配 sel
+//	a, b                    匹配 a 或 b
+//
+// 例如 where 之内, 第一个子节点为 a 的 field:
+//
+//	q := MustCompile(`where field:has(> :first[text=a])`)
+//	for _, n := range q.Select(root) {
+//		...
+//	}
+//
+// 与 Rewrite 一起使用时, 用 MatchCursor 判断当前节点:
+//
+//	Rewrite(root, func(c *Cursor) bool {
+//		if q.MatchCursor(c) {
+//			...
+//		}
+//		return true
+//	}, nil)
+//
+// 语法错误为 *QueryError, 位置是 src 中的字节偏移.
+
+type Query struct {
+	src  string
+	sels []*selector
+}
+
+// 选择器的语法错误, Pos 为 src 中的字节偏移
+type QueryError struct {
+	Pos int
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%d: %s", e.Pos, e.Msg)
+}
+
+// 路径上的一个节点, i 为它在父节点 Elts 中的下标, 不知道时为 -1
+type step struct {
+	n *Node
+	i int
+}
+
+// 复合选择器之间以 combs 连接, ' ' 为后代, '>' 为子节点.
+// :has 中的选择器相对于 :has 所在的节点, lead 为开头的连接符.
+type selector struct {
+	parts []compound
+	combs []rune
+	lead  rune
+}
+
+// path 从根到当前节点
+type compound []func(path []step) bool
+
+func (c compound) match(path []step) bool {
+	for _, f := range c {
+		if !f(path) {
+			return false
+		}
+	}
+	return true
+}
+
+func Compile(src string) (q *Query, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			qe, ok := e.(*QueryError)
+			if !ok {
+				panic(e)
+			}
+			q, err = nil, qe
+		}
+	}()
+	p := &qparser{s: []rune(src)}
+	sels := p.list(false)
+	if p.spaces(); p.pos < len(p.s) {
+		p.fail(fmt.Sprintf("unexpected %q", p.s[p.pos]))
+	}
+	return &Query{src: src, sels: sels}, nil
+}
+
+func MustCompile(src string) *Query {
+	q, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+func (q *Query) String() string {
+	return q.src
+}
+
+// 按先序返回 root 中 (包括 root) 匹配的节点
+func (q *Query) Select(root *Node) []*Node {
+	nodes := make([]*Node, 0)
+	if root != nil {
+		descendants([]step{{root, -1}}, true, func(path []step) bool {
+			if q.match(path) {
+				nodes = append(nodes, path[len(path)-1].n)
+			}
+			return false
+		})
+	}
+	return nodes
+}
+
+// 第一个匹配的节点, 没有时为 nil
+func (q *Query) First(root *Node) *Node {
+	var found *Node
+	if root != nil {
+		descendants([]step{{root, -1}}, true, func(path []step) bool {
+			if q.match(path) {
+				found = path[len(path)-1].n
+			}
+			return found != nil
+		})
+	}
+	return found
+}
+
+// path 为从根到节点的各个节点, 判断最后一个是否匹配.
+// 位置 (:first 等) 按指针在父节点中查找, 同一个节点在 Elts 中出现多次时取第一个;
+// 在 Rewrite 中应当用 MatchCursor.
+func (q *Query) Match(path []*Node) bool {
+	steps := make([]step, len(path))
+	for i, n := range path {
+		steps[i] = step{n, -1}
+	}
+	return q.match(steps)
+}
+
+// Rewrite 的当前节点是否匹配, 位置取 Cursor 中的下标
+func (q *Query) MatchCursor(c *Cursor) bool {
+	n := c.Node()
+	if n == nil {
+		return false
+	}
+	steps := make([]step, 0, len(c.path)+1)
+	for i, p := range c.path {
+		steps = append(steps, step{p, c.indices[i]})
+	}
+	return q.match(append(steps, step{n, c.Index()}))
+}
+
+func (q *Query) match(path []step) bool {
+	for _, s := range q.sels {
+		if s.match(len(s.parts)-1, path, -1) {
+			return true
+		}
+	}
+	return false
+}
+
+// 同 Compile(src) 后 Select(root)
+func QueryAll(root *Node, src string) ([]*Node, error) {
+	q, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return q.Select(root), nil
+}
+
+// 先序访问 path 最后一个节点的后代, self 为真时包括它自己, f 返回 true 时停止
+func descendants(path []step, self bool, f func(path []step) bool) bool {
+	if self && f(path) {
+		return true
+	}
+	for i, e := range path[len(path)-1].n.Elts {
+		if e != nil && descendants(append(path[:len(path):len(path)], step{e, i}), true, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// parts[:j+1] 是否匹配 path, 匹配的节点在 path 中的下标都要大于 scope
+func (s *selector) match(j int, path []step, scope int) bool {
+	if !s.parts[j].match(path) {
+		return false
+	}
+	last := len(path) - 1
+	if j == 0 {
+		return s.lead != '>' || last-1 == scope
+	}
+	if s.combs[j-1] == '>' {
+		return last-1 > scope && s.match(j-1, path[:last], scope)
+	}
+	for k := last; k > scope+1; k-- {
+		if s.match(j-1, path[:k], scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// 节点在父节点 Elts 中的下标和兄弟节点个数, 不知道下标时按指针查找
+func siblingIndex(path []step) (int, int) {
+	if len(path) < 2 {
+		return 0, 1
+	}
+	last, elts := path[len(path)-1], path[len(path)-2].n.Elts
+	if last.i >= 0 {
+		return last.i, len(elts)
+	}
+	for i, e := range elts {
+		if e == last.n {
+			return i, len(elts)
+		}
+	}
+	return 0, len(elts)
+}
+
+type qparser struct {
+	s   []rune
+	pos int
+}
+
+// 位置换算为字节偏移
+func (p *qparser) fail(msg string) {
+	panic(&QueryError{Pos: len(string(p.s[:p.pos])), Msg: msg})
+}
+
+func (p *qparser) peek() rune {
+	if p.pos < len(p.s) {
+		return p.s[p.pos]
+	}
+	return 0
+}
+
+func (p *qparser) spaces() bool {
+	start := p.pos
+	for p.pos < len(p.s) && unicode.IsSpace(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *qparser) expect(r rune) {
+	if p.spaces(); p.peek() != r {
+		p.fail(fmt.Sprintf("expected %q", r))
+	}
+	p.pos++
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_$@.", r)
+}
+
+func (p *qparser) name() string {
+	start := p.pos
+	for p.pos < len(p.s) && isNameRune(p.s[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		p.fail("expected name")
+	}
+	return string(p.s[start:p.pos])
+}
+
+// 带引号的字符串, 或者到 ] 为止的原文 (去掉末尾的空白)
+func (p *qparser) value() string {
+	q := p.peek()
+	if q != '"' && q != '\'' {
+		start := p.pos
+		for p.pos < len(p.s) && p.s[p.pos] != ']' {
+			p.pos++
+		}
+		return strings.TrimRightFunc(string(p.s[start:p.pos]), unicode.IsSpace)
+	}
+	var b strings.Builder
+	for p.pos++; p.pos < len(p.s); p.pos++ {
+		switch r := p.s[p.pos]; {
+		case r == q:
+			p.pos++
+			return b.String()
+		case r == '\\' && p.pos+1 < len(p.s):
+			p.pos++
+			b.WriteRune(p.s[p.pos])
+		default:
+			b.WriteRune(r)
+		}
+	}
+	p.fail("unterminated string")
+	return ""
+}
+
+// 以逗号分隔的选择器
+func (p *qparser) list(relative bool) []*selector {
+	sels := []*selector{p.selector(relative)}
+	for p.spaces(); p.peek() == ','; p.spaces() {
+		p.pos++
+		sels = append(sels, p.selector(relative))
+	}
+	return sels
+}
+
+func (p *qparser) selector(relative bool) *selector {
+	s := &selector{}
+	if p.spaces(); relative && p.peek() == '>' {
+		p.pos++
+		s.lead = '>'
+	}
+	p.spaces()
+	s.parts = append(s.parts, p.compound())
+	for {
+		ws := p.spaces()
+		switch r := p.peek(); {
+		case r == '>':
+			p.pos++
+			p.spaces()
+			s.combs = append(s.combs, '>')
+		case ws && r != 0 && r != ',' && r != ')':
+			s.combs = append(s.combs, ' ')
+		default:
+			return s
+		}
+		s.parts = append(s.parts, p.compound())
+	}
+}
+
+func (p *qparser) compound() compound {
+	c := make(compound, 0)
+	switch r := p.peek(); {
+	case r == '*':
+		p.pos++
+		c = append(c, func([]step) bool { return true })
+	case isNameRune(r):
+		tp := p.name()
+		c = append(c, func(path []step) bool { return path[len(path)-1].n.Type == tp })
+	}
+	for {
+		switch p.peek() {
+		case '[':
+			p.pos++
+			c = append(c, p.attr())
+		case ':':
+			p.pos++
+			c = append(c, p.pseudo())
+		default:
+			if len(c) == 0 {
+				p.fail("expected selector")
+			}
+			return c
+		}
+	}
+}
+
+var attrs = map[string]func(*Node) string{
+	"text": func(n *Node) string { return n.Text },
+	"type": func(n *Node) string { return n.Type },
+	"op":   func(n *Node) string { return n.Op },
+}
+
+var attrOps = map[string]func(s, v string) bool{
+	"=":  func(s, v string) bool { return s == v },
+	"!=": func(s, v string) bool { return s != v },
+	"^=": strings.HasPrefix,
+	"$=": strings.HasSuffix,
+	"*=": strings.Contains,
+}
+
+func (p *qparser) attr() func([]step) bool {
+	p.spaces()
+	// 属性名只有字母, 不能用 name, 否则 [text$=a] 中的 $ 会被读进属性名
+	start := p.pos
+	for p.pos < len(p.s) && unicode.IsLetter(p.s[p.pos]) {
+		p.pos++
+	}
+	name := string(p.s[start:p.pos])
+	get, ok := attrs[name]
+	if !ok {
+		p.pos = start
+		p.fail(fmt.Sprintf("unknown attribute %q", name))
+	}
+	p.spaces()
+	if p.peek() == ']' {
+		p.pos++
+		return func(path []step) bool { return get(path[len(path)-1].n) != "" }
+	}
+	// 只在属性名之后读运算符, 之后的 ^ $ 等属于值
+	start = p.pos
+	op := ""
+	for _, o := range []string{"!=", "^=", "$=", "*=", "~=", "="} {
+		if strings.HasPrefix(string(p.s[p.pos:]), o) {
+			op = o
+			p.pos += len(o)
+			break
+		}
+	}
+	if op == "" {
+		p.fail("expected operator")
+	}
+	p.spaces()
+	vpos, v := p.pos, p.value()
+	p.expect(']')
+	if op == "~=" {
+		re, err := regexp.Compile(v)
+		if err != nil {
+			p.pos = vpos
+			p.fail(err.Error())
+		}
+		return func(path []step) bool { return re.MatchString(get(path[len(path)-1].n)) }
+	}
+	f := attrOps[op]
+	return func(path []step) bool { return f(get(path[len(path)-1].n), v) }
+}
+
+func (p *qparser) pseudo() func([]step) bool {
+	start := p.pos
+	switch name := p.name(); name {
+	case "first":
+		return func(path []step) bool { i, _ := siblingIndex(path); return i == 0 }
+	case "last":
+		return func(path []step) bool { i, n := siblingIndex(path); return i == n-1 }
+	case "empty":
+		return func(path []step) bool { return len(path[len(path)-1].n.Elts) == 0 }
+	case "nth":
+		p.expect('(')
+		p.spaces()
+		npos := p.pos
+		for p.pos < len(p.s) && (p.s[p.pos] == '-' || unicode.IsDigit(p.s[p.pos])) {
+			p.pos++
+		}
+		k, err := strconv.Atoi(string(p.s[npos:p.pos]))
+		if err != nil || k == 0 {
+			p.pos = npos
+			p.fail("expected non-zero integer")
+		}
+		p.expect(')')
+		return func(path []step) bool {
+			i, n := siblingIndex(path)
+			if k < 0 {
+				return i == n+k
+			}
+			return i == k-1
+		}
+	case "has":
+		p.expect('(')
+		sels := p.list(true)
+		p.expect(')')
+		return func(path []step) bool {
+			scope := len(path) - 1
+			return descendants(path, false, func(sub []step) bool {
+				for _, s := range sels {
+					if s.match(len(s.parts)-1, sub, scope) {
+						return true
+					}
+				}
+				return false
+			})
+		}
+	case "not":
+		p.expect('(')
+		q := &Query{sels: p.list(false)}
+		p.expect(')')
+		return func(path []step) bool { return !q.match(path) }
+	default:
+		p.pos = start
+		p.fail(fmt.Sprintf("unknown pseudo-class %q", name))
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func queryTree() *Node {
+	SetParameters()
+	return ParseSexp("(select (where (field a x) (field b a) (from (field a y))) (field a z))")[0]
+}
+
+func selectDump(t *testing.T, root *Node, src string) string {
+	q, err := Compile(src)
+	if err != nil {
+		t.Fatalf("%q: %v", src, err)
+	}
+	var ss []string
+	for _, n := range q.Select(root) {
+		ss = append(ss, dump(n))
+	}
+	return strings.Join(ss, " ")
+}
+
+func TestQuerySelect(t *testing.T) {
+	root := queryTree()
+	for _, c := range []struct{ src, want string }{
+		{"token[text=from]", "token:from"},
+		{"sexp:has(> :first[text=where]) sexp:has(> :first[text=field]):has(> :nth(2)[text=a])", "(sexp token:field token:a token:x) (sexp token:field token:a token:y)"},
+		{"sexp:has(> :first[text=where]) > sexp:has(> :first[text=field])", "(sexp token:field token:a token:x) (sexp token:field token:b token:a)"},
+		{"token[text^=fi]:not(:first)", ""},
+		{"token:last[text~='^[xyz]$']", "token:x token:y token:z"},
+		{"token:nth(-1)[text=a]", "token:a"},
+		{"sexp:empty, token[text=select]", "token:select"},
+		{"token[text=from], token[text=select]", "token:select token:from"},
+	} {
+		if got := selectDump(t, root, c.src); got != c.want {
+			t.Errorf("%q: got %s, want %s", c.src, got, c.want)
+		}
+	}
+}
+
+// 只在属性名之后读运算符, 值中的 ^ $ 按原文匹配
+func TestQueryAttrValue(t *testing.T) {
+	root := &Node{Type: "list", Elts: []*Node{{Type: "token", Text: "a$"}, {Type: "token", Text: "^b"}, {Type: "token", Text: "c"}}}
+	for _, c := range []struct{ src, want string }{
+		{"[text=a$]", "token:a$"},
+		{"[text=^b]", "token:^b"},
+		{"[text $= $ ]", "token:a$"},
+		{"[text^=^]", "token:^b"},
+		{"token[text!=c]", "token:a$ token:^b"},
+	} {
+		if got := selectDump(t, root, c.src); got != c.want {
+			t.Errorf("%q: got %s, want %s", c.src, got, c.want)
+		}
+	}
+}
+
+// 同一个节点在 Elts 中出现多次时, 位置按路径上的下标计算
+func TestQuerySharedNode(t *testing.T) {
+	a := &Node{Type: "token", Text: "a"}
+	root := &Node{Type: "list", Elts: []*Node{a, a, a}}
+	for _, c := range []struct {
+		src  string
+		want int
+	}{
+		{"token:first", 1},
+		{"token:last", 1},
+		{"token:nth(2)", 1},
+		{"token", 3},
+	} {
+		if got := len(MustCompile(c.src).Select(root)); got != c.want {
+			t.Errorf("%q: got %d nodes, want %d", c.src, got, c.want)
+		}
+	}
+	var indices []int
+	q := MustCompile("token:last")
+	Rewrite(root, func(c *Cursor) bool {
+		if q.MatchCursor(c) {
+			indices = append(indices, c.Index())
+		}
+		return true
+	}, nil)
+	if len(indices) != 1 || indices[0] != 2 {
+		t.Errorf("MatchCursor matched at %v, want [2]", indices)
+	}
+}
+
+func TestQueryMatchCursor(t *testing.T) {
+	root := queryTree()
+	q := MustCompile("sexp:nth(2) > token:first")
+	var got []string
+	Rewrite(root, func(c *Cursor) bool {
+		if q.MatchCursor(c) {
+			got = append(got, c.Node().Text)
+		}
+		return true
+	}, nil)
+	if strings.Join(got, " ") != "where field field" {
+		t.Errorf("got %v", got)
+	}
+}
+
+// 错误的位置是字节偏移
+func TestQueryError(t *testing.T) {
+	for _, c := range []struct{ src, want string }{
+		{"field[text=a", `12: expected ']'`},
+		{"字段[名=a]", `7: unknown attribute "名"`},
+		{"字段:nth(0)", `11: expected non-zero integer`},
+		{"字段 >", `8: expected selector`},
+		{"a:foo", `2: unknown pseudo-class "foo"`},
+		{"[text<a]", `5: expected operator`},
+	} {
+		_, err := Compile(c.src)
+		if _, ok := err.(*QueryError); !ok || err.Error() != c.want {
+			t.Errorf("%q: error = %v, want %s", c.src, err, c.want)
+		}
+	}
+	if _, err := QueryAll(queryTree(), "[text"); err == nil {
+		t.Error("want error")
+	}
+}
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 错误恢复
+// --------------------------------------------
+//
+// c 失败 (包括剪枝后抛出 ParseError) 时跳过一段输入, 用一个 error 节点代替, 然后继续解析.
+// 被跳过的 token 放在 error 节点的 Elts 中, Text 为错误信息.
+// 没有可跳过的输入时 (输入结束, 或遇到外层的闭括号) 仍然失败, 交给外层处理.
+
+// 跳过 toks[:n], 生成 error 节点, err 为 c 中剪枝后抛出的错误, 可以为 nil.
+// 生成的解析器 (parsec-gen) 也用它构造 error 节点
+func SkippedNode(toks []*Node, n int, err *ParseError) *Node {
+	elts := make([]*Node, n)
+	copy(elts, toks[:n])
+	texts := make([]string, 0, 4)
+	for i, tok := range elts {
+		if i == 3 && n > 4 {
+			texts = append(texts, "...")
+			break
+		}
+		texts = append(texts, tok.Text)
+	}
+	msg := fmt.Sprintf("unexpected %q", strings.Join(texts, " "))
+	if err != nil {
+		msg = err.Error()
+	}
+	return &Node{
+		Type:  ErrorType,
+		Start: toks[0].Start,
+		End:   toks[n-1].End,
+		Elts:  elts,
+		Text:  msg,
+	}
+}
+
+// c 失败时跳过 token, 直到 sync 可以匹配 (sync 不被消耗) 或输入结束
+// @recover-until
+func AtRecoverUntil(c, sync Combinator) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			t, r, err := try(c, toks, stk, ctx)
+			if t != nil {
+				return t, r
+			}
+			n := 0
+			for n < len(toks) {
+				if t, _ := ApplyCheck(sync, toks[n:], stk, ctx); t != nil {
+					break
+				}
+				n++
+			}
+			if n == 0 && err != nil {
+				panic(err)
+			} else if n == 0 {
+				return nil, nil
+			}
+			return []*Node{SkippedNode(toks, n, err)}, toks[n:]
+		}
+	}
+}
+
+// c 失败时跳过一个完整的项: 一个 token, 或者从开括号到与之配对的闭括号.
+// pairs 依次为开括号和闭括号, 如 "(", ")", "[", "]", 跳过时所有括号一起配对,
+// 其中不配对的闭括号当作普通 token 跳过. 遇到外层的闭括号时停止, 它属于外层结构.
+// @recover
+func AtRecover(c Combinator, pairs ...string) Combinator {
+	if len(pairs)%2 != 0 {
+		panic("AtRecover: pairs must be open/close pairs")
+	}
+	closeOf := make(map[string]string)
+	isClose := make(map[string]bool)
+	for i := 0; i < len(pairs); i += 2 {
+		closeOf[pairs[i]] = pairs[i+1]
+		isClose[pairs[i+1]] = true
+	}
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			t, r, err := try(c, toks, stk, ctx)
+			if t != nil {
+				return t, r
+			}
+			n, want := 0, make([]string, 0)
+			for n < len(toks) {
+				tok := toks[n]
+				if IsTokenType(tok) && isClose[tok.Text] {
+					if len(want) == 0 {
+						break
+					}
+					if want[len(want)-1] == tok.Text {
+						want = want[:len(want)-1]
+					}
+				} else if close, ok := closeOf[tok.Text]; ok && IsTokenType(tok) {
+					want = append(want, close)
+				}
+				n++
+				if len(want) == 0 {
+					break
+				}
+			}
+			if n == 0 && err != nil {
+				panic(err)
+			} else if n == 0 {
+				return nil, nil
+			}
+			return []*Node{SkippedNode(toks, n, err)}, toks[n:]
+		}
+	}
+}
+
+// 收集树中所有的 error 节点
+func Errors(nodes []*Node) []*Node {
+	errs := make([]*Node, 0)
+	var loop func([]*Node)
+	loop = func(nodes []*Node) {
+		for _, n := range nodes {
+			if IsError(n) {
+				errs = append(errs, n)
+			} else {
+				loop(n.Elts)
+			}
+		}
+	}
+	loop(nodes)
+	return errs
+}
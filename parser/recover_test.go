@@ -0,0 +1,72 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAtRecoverUntil(t *testing.T) {
+	SetParameters()
+	SetDelims(";")
+	stmt := B["@seq"](S["$$"]("let"), P["$pred"](Preds["id"]))
+	c := B["@*"](B["@seq"](J["@recover-until"](stmt, S["$$"](";")), S["@_"](";")))
+	got, _ := Eval(c, Scan("let a; x y; let b;"))
+	if want := "token:let token:a (error token:x token:y) token:let token:b"; dumps(got) != want {
+		t.Fatalf("got %s, want %s", dumps(got), want)
+	}
+	if errs := Errors(got); len(errs) != 1 || errs[0].Text != `unexpected "x y"` || errs[0].Start != 7 || errs[0].End != 10 {
+		t.Errorf("Errors = %v", errs)
+	}
+	// 没有可跳过的输入时失败
+	if got, _ := Eval(J["@recover-until"](stmt, S["$$"](";")), Scan("; x")); got != nil {
+		t.Errorf("got %s, want <nil>", dumps(got))
+	}
+}
+
+func TestAtRecoverNested(t *testing.T) {
+	SetParameters()
+	item := P["$pred"](Preds["id"])
+	c := B["@seq"](S["@_"]("("), B["@*"](AtRecover(item, "(", ")", "[", "]")), S["@_"](")"))
+	for _, cc := range []struct{ src, want string }{
+		{`(a 1 b)`, `token:a (error number:1) token:b`},
+		// 跳过的部分中 [ ] 与 ( ) 一起配对
+		{`(a (x [y )] z) b)`, `token:a (error token:( token:x token:[ token:y token:) token:] token:z token:)) token:b`},
+		{`(a [x (y) z] b)`, `token:a (error token:[ token:x token:( token:y token:) token:z token:]) token:b`},
+		// 不配对的闭括号当作普通 token 跳过
+		{`(a (x ] y) b)`, `token:a (error token:( token:x token:] token:y token:)) token:b`},
+	} {
+		got, _ := Eval(c, Scan(cc.src))
+		if dumps(got) != cc.want {
+			t.Errorf("%s: got %s, want %s", cc.src, dumps(got), cc.want)
+		}
+	}
+}
+
+func TestAtRecoverCut(t *testing.T) {
+	SetParameters()
+	// 剪枝后的错误作为 error 节点的 Text
+	call := T["@="]("call", B["@seq"](S["@_"]("("), S["@_"]("f"), B["@^"](P["$pred"](Preds["number"]), S["@_"](")"))))
+	c := B["@*"](AtRecover(B["@or"](call, P["$pred"](Preds["id"])), "(", ")"))
+	got, _ := Eval(c, Scan("a (f x) (f 1) b"))
+	if want := "token:a (error token:( token:f token:x token:)) (call number:1) token:b"; dumps(got) != want {
+		t.Fatalf("got %s, want %s", dumps(got), want)
+	}
+	if errs := Errors(got); len(errs) != 1 || !strings.Contains(errs[0].Text, `unexpected "x"`) {
+		t.Errorf("Errors = %v", errs)
+	}
+}
+
+func TestParseSQLRecover(t *testing.T) {
+	// AS 子句剪枝后出错, 被跳过的部分中含有 [ ]
+	got := ParseSQL(`(SELECT (FROM t) (WHERE (AS [x )] ) y) a)`)
+	errs := Errors(got)
+	if len(errs) != 1 {
+		t.Fatalf("Errors = %d in %s", len(errs), dumps(got))
+	}
+	if got, want := dumps(errs[0].Elts), "token:( token:AS token:[ token:x token:) token:] token:)"; got != want {
+		t.Errorf("skipped %s, want %s", got, want)
+	}
+	if got, want := dumps(got), "(select (from token:t) (where (error token:( token:AS token:[ token:x token:) token:] token:)) token:y) token:a)"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"math/big"
+	"strconv"
+	"testing"
+)
+
+// 扫描结果的紧凑写法, 附带起止偏移
+func scanDump(s string) string {
+	ret := ""
+	for _, tok := range Scan(s) {
+		if tok.Type == EofType {
+			continue
+		}
+		ret += tok.Type + ":" + tok.Text + "@" + strconv.Itoa(tok.Start) + "-" + strconv.Itoa(tok.End) + " "
+	}
+	return ret
+}
+
+func TestScanUnicode(t *testing.T) {
+	SetParameters()
+	for _, c := range []struct{ src, want string }{
+		// 偏移是字节偏移
+		{`(名字 café)`, `token:(@0-1 token:名字@1-7 token:café@8-13 token:)@13-14 `},
+		// 全角空格, 不换行空格也是空白
+		{"a\u3000b\u00a0c", `token:a@0-1 token:b@4-5 token:c@7-8 `},
+		{`#\λ ?\中`, `character:λ@0-4 character:中@5-10 `},
+	} {
+		if got := scanDump(c.src); got != c.want {
+			t.Errorf("Scan(%q) = %s, want %s", c.src, got, c.want)
+		}
+	}
+}
+
+func TestCharClasses(t *testing.T) {
+	for _, c := range []struct {
+		f    func(string) bool
+		s    string
+		want bool
+	}{
+		{IsAlpha, "é", true},
+		{IsAlpha, "中", true},
+		{IsAlpha, "ab", false},
+		{IsAlpha, "", false},
+		{IsDigit, "٣", true},
+		{IsDigit, "a", false},
+		{IsWhitespace, "\u3000", true},
+		{IsWhitespace, "\xff", false},
+		{IsId, "名字", true},
+		{IsId, "_x1", true},
+		{IsId, "x́", true},
+		{IsId, "1x", false},
+		{IsId, "a\xffb", false},
+		{IsId, "a-b", false},
+	} {
+		if got := c.f(c.s); got != c.want {
+			t.Errorf("%q: got %v, want %v", c.s, got, c.want)
+		}
+	}
+}
+
+func TestSetIdChars(t *testing.T) {
+	start, part := id_start, id_part
+	defer func() { SetIdStart(start); SetIdPart(part) }()
+	// lisp 风格的标识符
+	SetIdStart(func(r rune) bool { return r != '-' && !('0' <= r && r <= '9') })
+	SetIdPart(func(r rune) bool { return true })
+	if !IsId("set-car!") || IsId("-x") || !IsIdStart('a') || IsIdStart('1') || !IsIdPart('!') {
+		t.Error("custom identifier characters are not used")
+	}
+}
+
+func TestScanString(t *testing.T) {
+	SetParameters()
+	for _, c := range []struct{ src, text, value string }{
+		{`"a b"`, `a b`, `a b`},
+		{`"a\tb\"c\\"`, `a\tb\"c\\`, "a\tb\"c\\"},
+		{`"中\x41\101"`, `中\x41\101`, "中AA"},
+		{`"中文"`, `中文`, `中文`},
+		{`""`, ``, ``},
+	} {
+		toks := Scan(c.src)
+		if len(toks) != 1 || !IsStrType(toks[0]) || toks[0].Text != c.text || toks[0].Value != c.value || toks[0].End != len(c.src) {
+			t.Errorf("Scan(%q) = %s", c.src, scanDump(c.src))
+		}
+	}
+}
+
+func TestScanStringErrors(t *testing.T) {
+	SetParameters()
+	for _, c := range []struct{ src, want string }{
+		{`a "bc`, `2: unterminated string`},
+		{`"bc\`, `0: unterminated string`},
+		{`"b\qc"`, `2: invalid escape "\\q"`},
+	} {
+		if _, err := ScanErr(c.src); err == nil || err.Error() != c.want {
+			t.Errorf("ScanErr(%q) error = %v, want %s", c.src, err, c.want)
+		}
+	}
+}
+
+func TestScanRawString(t *testing.T) {
+	SetParameters()
+	defer SetRawQuotationMarks()
+	// ` 同时是 SetParameters 中的分隔符
+	SetRawQuotationMarks("`")
+	toks := Scan("(f `a\\n\"b` x)")
+	if len(toks) != 5 || !IsStrType(toks[2]) || toks[2].Text != `a\n"b` || toks[2].Value != `a\n"b` {
+		t.Errorf("got %s", scanDump("(f `a\\n\"b` x)"))
+	}
+	if _, err := ScanErr("`abc"); err == nil || err.Error() != "0: unterminated string" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestScanDoubledQuote(t *testing.T) {
+	SetParameters()
+	defer SetDoubledQuoteEscape(false)
+	SetQuotationMarks("'")
+	SetDoubledQuoteEscape(true)
+	toks := Scan(`'it''s' 'x'`)
+	if len(toks) != 2 || toks[0].Value != "it's" || toks[0].Text != "it''s" || toks[1].Value != "x" {
+		t.Errorf("got %s", scanDump(`'it''s' 'x'`))
+	}
+}
+
+func TestScanNumber(t *testing.T) {
+	SetParameters()
+	for _, c := range []struct {
+		src   string
+		value interface{}
+	}{
+		{"0", int64(0)},
+		{"42", int64(42)},
+		{"1_000", int64(1000)},
+		{"0x1F", int64(31)},
+		{"0o17", int64(15)},
+		{"017", int64(15)},
+		{"0b101", int64(5)},
+		{"1.5", 1.5},
+		{".5", 0.5},
+		{"1e3", 1000.0},
+		{"08.5", 8.5},
+		{"09e1", 90.0},
+		{"0x1p4", 16.0},
+	} {
+		toks := Scan(c.src)
+		if len(toks) != 1 || !IsNumber(toks[0]) || toks[0].Value != c.value || toks[0].Text != c.src {
+			t.Errorf("Scan(%q) = %s %v, want %v", c.src, scanDump(c.src), toks[0].Value, c.value)
+		}
+	}
+	if v, ok := Scan("100000000000000000000")[0].Value.(*big.Int); !ok || v.String() != "100000000000000000000" {
+		t.Errorf("big int = %v", Scan("100000000000000000000")[0].Value)
+	}
+	// 不是数字的仍是普通 token
+	// 08, 09 不是合法的八进制数
+	for _, src := range []string{"1.2.3", "12abc", "0x", "1e", "08", "0_9"} {
+		if toks := Scan(src); len(toks) != 1 || !IsTokenType(toks[0]) || toks[0].Text != src {
+			t.Errorf("Scan(%q) = %s", src, scanDump(src))
+		}
+	}
+	if got, want := scanDump("(f 1)"), "token:(@0-1 token:f@1-2 number:1@3-4 token:)@4-5 "; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+// 数字 token 可以用 $$ 按原文匹配, numeral 是 number 的别名
+func TestNumberPreds(t *testing.T) {
+	SetParameters()
+	c := B["@seq"](S["$$"]("LIMIT"), S["$$"]("10"), P["$pred"](Preds["numeral"]))
+	if got, _ := Eval(c, Scan("LIMIT 10 0x10")); dumps(got) != "token:LIMIT number:10 number:0x10" {
+		t.Errorf("got %s", dumps(got))
+	}
+	if got, _ := Eval(c, Scan("LIMIT 010 1")); got != nil {
+		t.Errorf("got %s, want <nil>", dumps(got))
+	}
+}
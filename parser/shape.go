@@ -0,0 +1,52 @@
+package parser
+
+// 运算符节点的形状
+// --------------------------------------------
+//
+// @infix @prefix @postfix 和 OperatorTable 用 Builder 构造运算符节点.
+// kind 为 infix, prefix 或 postfix, op 为运算符, operands 为操作数.
+//
+//	TripleBuilder   默认, 运算符作为子节点   (additive a + b)
+//	BinaryBuilder   运算符放在 Op 中        (additive a b), Op 为 "+"
+//	FlatBuilder     同 BinaryBuilder, 左操作数是同类型同运算符的中缀节点时合并,
+//	                a + b + c 得到 (additive a b c), Op 为 "+"
+//
+// FlatBuilder 只合并左操作数, 因此只适用于左结合的运算符; a - (b - c) 的右操作数不会合并.
+// 运算符应当恰好产生一个节点, 否则 (如用 @_ 匹配运算符) 不调用 Builder, 按 TripleBuilder 的形状拼接.
+//
+// 例如
+//
+//	AtInfixWith(FlatBuilder, "or", and, S["@_"]("or"), "left")
+//	InfixLeftOp("additive", op("+")).With(FlatBuilder)
+type Builder func(kind, tp string, op *Node, operands []*Node) *Node
+
+func TripleBuilder(kind, tp string, op *Node, operands []*Node) *Node {
+	var elts []*Node
+	switch kind {
+	case "infix":
+		elts = []*Node{operands[0], op, operands[1]}
+	case "prefix":
+		elts = []*Node{op, operands[0]}
+	default:
+		elts = []*Node{operands[0], op}
+	}
+	return &Node{Type: tp, Start: elts[0].Start, End: elts[len(elts)-1].End, Elts: elts}
+}
+
+func BinaryBuilder(kind, tp string, op *Node, operands []*Node) *Node {
+	n := TripleBuilder(kind, tp, op, operands)
+	n.Elts, n.Op = append(make([]*Node, 0, len(operands)), operands...), op.Text
+	return n
+}
+
+func FlatBuilder(kind, tp string, op *Node, operands []*Node) *Node {
+	n := BinaryBuilder(kind, tp, op, operands)
+	if kind != "infix" {
+		return n
+	}
+	// 只合并左操作数, 右操作数如 a - (b - c) 中的 b - c 不能合并
+	if left := operands[0]; left.Type == tp && left.Op != "" && left.Op == n.Op {
+		n.Elts = append(append(make([]*Node, 0, len(left.Elts)+1), left.Elts...), operands[1])
+	}
+	return n
+}
@@ -0,0 +1,65 @@
+package parser
+
+import "testing"
+
+func TestBuilders(t *testing.T) {
+	SetCalcParameters()
+	defer func() { SetOperators(); SetParameters() }()
+	num := P["$pred"](IsNumber)
+	for _, c := range []struct {
+		name string
+		b    Builder
+		want string
+	}{
+		{"triple", TripleBuilder, "(add (add number:1 token:+ number:2) token:+ number:3)"},
+		{"binary", BinaryBuilder, "(add[+] (add[+] number:1 number:2) number:3)"},
+		{"flat", FlatBuilder, "(add[+] number:1 number:2 number:3)"},
+	} {
+		if got, _ := Eval(AtInfixWith(c.b, "add", num, op("+"), "left"), Scan("1 + 2 + 3")); dumps(got) != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, dumps(got), c.want)
+		}
+	}
+	if got, _ := Eval(AtPrefixWith(BinaryBuilder, "neg", num, op("-")), Scan("- - 1")); dumps(got) != "(neg[-] (neg[-] number:1))" {
+		t.Errorf("prefix: got %s", dumps(got))
+	}
+	if got, _ := Eval(AtPostfixWith(FlatBuilder, "inc", num, op("++")), Scan("1 ++ ++")); dumps(got) != "(inc[++] (inc[++] number:1))" {
+		t.Errorf("postfix: got %s", dumps(got))
+	}
+	// 不同运算符不合并
+	if got, _ := Eval(AtInfixWith(FlatBuilder, "add", num, B["@or"](op("+"), op("-")), "left"), Scan("1 + 2 - 3 - 4")); dumps(got) != "(add[-] (add[+] number:1 number:2) number:3 number:4)" {
+		t.Errorf("flat: got %s", dumps(got))
+	}
+}
+
+// 右操作数不合并: a - (b - c) 不能变成 (- a b c)
+func TestFlatBuilderRight(t *testing.T) {
+	SetCalcParameters()
+	defer func() { SetOperators(); SetParameters() }()
+	var expr Combinator
+	term := B["@or"](P["$pred"](IsNumber), B["@seq"](S["@_"]("("), func() Parser { return expr() }, S["@_"](")")))
+	expr = OperatorTable{{InfixLeftOp("sub", op("-")).With(FlatBuilder)}}.Build(term)
+	for _, c := range []struct{ src, want string }{
+		{"1 - (2 - 3)", "(sub[-] number:1 (sub[-] number:2 number:3))"},
+		{"(1 - 2) - 3", "(sub[-] number:1 number:2 number:3)"},
+		{"1 - 2 - 3 - 4", "(sub[-] number:1 number:2 number:3 number:4)"},
+	} {
+		if got, _ := Eval(expr, Scan(c.src)); dumps(got) != c.want {
+			t.Errorf("%q: got %s, want %s", c.src, dumps(got), c.want)
+		}
+	}
+}
+
+func TestOperatorWith(t *testing.T) {
+	SetCalcParameters()
+	defer func() { SetOperators(); SetParameters() }()
+	term := P["$pred"](func(n *Node) bool { return IsTokenType(n) && IsId(n.Text) })
+	table := OperatorTable{
+		{PrefixOp("neg", op("-")).With(BinaryBuilder)},
+		{InfixLeftOp("add", op("+")).With(BinaryBuilder)},
+		// 运算符不产生节点时不使用 Builder
+		{InfixLeftOp("or", S["@_"]("or")).With(BinaryBuilder)},
+	}
+	if got, _ := Eval(table.Build(term), Scan("- a + b or c")); dumps(got) != "(or (add[+] (neg[-] token:a) token:b) token:c)" {
+		t.Errorf("got %s", dumps(got))
+	}
+}
@@ -0,0 +1,187 @@
+package parser
+
+import "strconv"
+
+// 解析状态
+// --------------------------------------------
+//
+// Eval 传给解析器的 ctx 是 *ParseState, 其中有缓存 (:: 和具名规则使用) 和用户状态 User.
+// 用户状态用于上下文相关的解析, 比如记录已声明的类型名, 之后把这些名字当作类型解析:
+//
+//	decl := UpdateState(B["@seq"](S["@_"]("type"), identifier), func(user interface{}, ns []*Node) interface{} {
+//		types := map[string]bool{ns[0].Text: true}
+//		for name := range user.(map[string]bool) {
+//			types[name] = true
+//		}
+//		return types
+//	})
+//	typeName := StatePred(func(user interface{}, n *Node) bool { return user.(map[string]bool)[n.Text] })
+//
+// 解析失败 (包括回溯到 @or 的下一个分支) 时, 其中对状态的修改会被撤销.
+// 向前看 (@!, @and 中除最后一个以外的, LookAhead, NotFollowedBy) 和被丢弃的结果
+// (如 @infix 末尾多余的运算符) 不会改变状态.
+// 撤销只是恢复旧的 User, 因此修改状态时应当返回新的值, 而不是原地修改旧值.
+// 缓存按状态区分: 每次修改都会得到新的版本号, 同一位置不同状态下的结果分别缓存.
+
+type ParseState struct {
+	User interface{}
+
+	memo  map[string]*memoEntry
+	gen   int      // 当前状态的版本号
+	next  int      // 已分配的最大版本号
+	rules []string // 正在解析的具名规则
+	more  bool     // 读到了 EvalStream 窗口末尾的占位 token
+}
+
+type memoEntry struct {
+	t, r []*Node
+	user interface{}
+	gen  int
+}
+
+func NewParseState(user interface{}) *ParseState {
+	return &ParseState{User: user, memo: make(map[string]*memoEntry)}
+}
+
+type snapshot struct {
+	user interface{}
+	gen  int
+}
+
+func (st *ParseState) save() snapshot {
+	return snapshot{st.User, st.gen}
+}
+
+func (st *ParseState) restore(s snapshot) {
+	st.User, st.gen = s.user, s.gen
+}
+
+func (st *ParseState) put(user interface{}) {
+	st.next++
+	st.User, st.gen = user, st.next
+}
+
+// key 加上状态版本号
+func (st *ParseState) key(key string) string {
+	return key + "#" + strconv.Itoa(st.gen)
+}
+
+func (st *ParseState) lookup(key string) (*memoEntry, bool) {
+	e, ok := st.memo[st.key(key)]
+	if ok {
+		st.User, st.gen = e.user, e.gen
+	}
+	return e, ok
+}
+
+// from 为解析前的状态, 失败的结果不改变状态
+func (st *ParseState) store(key string, from snapshot, t, r []*Node) {
+	if t == nil {
+		st.restore(from)
+	}
+	st.memo[key+"#"+strconv.Itoa(from.gen)] = &memoEntry{t: t, r: r, user: st.User, gen: st.gen}
+}
+
+// 运行 p, 失败时撤销其中对状态的修改
+func rollback(p Parser, toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+	st, ok := ctx.(*ParseState)
+	if !ok {
+		return p(toks, stk, ctx)
+	}
+	s := st.save()
+	t, r := p(toks, stk, ctx)
+	if t == nil {
+		st.restore(s)
+	}
+	return t, r
+}
+
+// 运行 p, 总是撤销其中对状态的修改, 用于向前看
+func lookahead(p Parser, toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+	s := mark(ctx)
+	t, r := p(toks, stk, ctx)
+	reset(ctx, s)
+	return t, r
+}
+
+// 记录当前状态, 丢弃之后的解析结果时用 reset 撤销其中对状态的修改
+func mark(ctx interface{}) snapshot {
+	if st, ok := ctx.(*ParseState); ok {
+		return st.save()
+	}
+	return snapshot{}
+}
+
+func reset(ctx interface{}, s snapshot) {
+	if st, ok := ctx.(*ParseState); ok {
+		st.restore(s)
+	}
+}
+
+// 根据当前状态选择解析器
+func GetState(f func(user interface{}) Combinator) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			var user interface{}
+			if st, ok := ctx.(*ParseState); ok {
+				user = st.User
+			}
+			return ApplyCheck(f(user), toks, stk, ctx)
+		}
+	}
+}
+
+// 设置状态, 不消耗 token
+func PutState(user interface{}) Combinator {
+	return ModifyState(func(interface{}) interface{} { return user })
+}
+
+func ModifyState(f func(user interface{}) interface{}) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			if st, ok := ctx.(*ParseState); ok {
+				st.put(f(st.User))
+			}
+			return make([]*Node, 0), toks
+		}
+	}
+}
+
+// c 成功后, 用其结果更新状态
+func UpdateState(c Combinator, f func(user interface{}, nodes []*Node) interface{}) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			t, r := ApplyCheck(c, toks, stk, ctx)
+			if st, ok := ctx.(*ParseState); ok && t != nil {
+				st.put(f(st.User, t))
+			}
+			return t, r
+		}
+	}
+}
+
+// 匹配一个 token, 判断时可以使用状态
+func StatePred(f func(user interface{}, n *Node) bool) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			var user interface{}
+			if st, ok := ctx.(*ParseState); ok {
+				user = st.User
+			}
+			reachMore(toks, ctx)
+			if len(toks) == 0 || !f(user, toks[0]) {
+				return nil, nil
+			}
+			return []*Node{toks[0]}, toks[1:]
+		}
+	}
+}
+
+// 同 EvalErr, 使用给定的状态, 结束后 st.User 为最终的用户状态
+func EvalState(c Combinator, toks []*Node, st *ParseState) ([]*Node, []*Node, error) {
+	t, r, err := try(c, toks, make([]*Pair, 0), st)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t, r, nil
+}
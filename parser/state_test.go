@@ -0,0 +1,49 @@
+package parser
+
+import "testing"
+
+// 解析 text 后把状态设为 text
+func putAfter(text string) Combinator {
+	return UpdateState(S["$$"](text), func(interface{}, []*Node) interface{} { return text })
+}
+
+// 修改状态后失败, 不自己撤销
+var dirty Combinator = func() Parser {
+	return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+		ctx.(*ParseState).put("dirty")
+		return nil, nil
+	}
+}
+
+func TestStateRollback(t *testing.T) {
+	SetParameters()
+	tok := P["$pred"](Preds["id"])
+	op := B["@or"](putAfter("+"), putAfter("-"))
+	cases := []struct {
+		name string
+		c    Combinator
+		src  string
+		want interface{}
+	}{
+		{"@or", B["@or"](B["@seq"](putAfter("a"), S["@_"]("x")), tok), "a b", "init"},
+		{"@! 成功的分支", B["@seq"](B["@!"](B["@seq"](putAfter("a"), S["@_"]("x"))), tok), "a b", "init"},
+		{"@!^", O["@!^"](dirty), "a", "init"},
+		{"@and", B["@and"](putAfter("a"), tok), "a", "init"},
+		{"@and 最后一个", B["@and"](tok, putAfter("a")), "a", "a"},
+		{"@look-ahead", O["@look-ahead"](putAfter("a")), "a", "init"},
+		{"@not-followed-by", O["@not-followed-by"](B["@seq"](putAfter("a"), S["@_"]("x"))), "a b", "init"},
+		{"@*^", B["@seq"](O["@*^"](dirty), tok), "a", "init"},
+		{"$glob^", B["@or"](O["$glob^"](dirty), tok), "a", "init"},
+		{"@infix 末尾的运算符", F["@infix-left"]("exp", tok, op), "a - b + c -", "+"},
+		{"ChainL1 末尾的运算符", ChainL1(tok, op, func(l, o, r []*Node) []*Node { return l }), "a - b + c -", "+"},
+	}
+	for _, c := range cases {
+		st := NewParseState("init")
+		if _, _, err := EvalState(c.c, Scan(c.src), st); err != nil {
+			t.Errorf("%s: error %v", c.name, err)
+		}
+		if st.User != c.want {
+			t.Errorf("%s: state = %v, want %v", c.name, st.User, c.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package parser
+
+// 常用组合子
+// --------------------------------------------
+//
+//	SepBy(c, sep)          零个或多个 c, 以 sep 分隔                    @sep-by
+//	SepBy1(c, sep)         一个或多个 c, 以 sep 分隔, 同 @.@
+//	SepEndBy(c, sep)       同 SepBy, 允许末尾多一个 sep, 如 `a, b,`     @sep-end-by
+//	Between(open, close, c) open c close, 只保留 c 的结果
+//	Count(n, c)            恰好 n 个 c
+//	Repeat(min, max, c)    min 到 max 个 c, max < 0 表示不限
+//	ManyTill(c, end)       零个或多个 c, 直到 end 匹配, 只保留 c 的结果  @many-till
+//	Option(c, def...)      c 不匹配时以 def 为结果
+//	LookAhead(c)           c 匹配时成功, 不消耗 token, 不产生节点      @look-ahead
+//	NotFollowedBy(c)       c 不匹配时成功, 不消耗 token, 不产生节点    @not-followed-by
+//	ChainL1(c, op, f)      c (op c)*, 用 f 从左向右合并
+//	ChainR1(c, op, f)      c (op c)*, 用 f 从右向左合并
+//
+// 这里的重复类组合子 (SepBy, SepBy1, SepEndBy, Repeat, ManyTill, ChainL1, ChainR1)
+// 在一轮没有消耗 token 时停止, 不会死循环. @* 和 @.@ 没有这个检查.
+
+func SepBy(c, sep Combinator) Combinator {
+	return AtWhy(SepBy1(c, sep))
+}
+
+// 同 @.@, sep c 没有消耗 token 时停止
+func SepBy1(c, sep Combinator) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			items, seps, r := chain(c, sep, toks, stk, ctx)
+			if items == nil {
+				return nil, nil
+			}
+			nodes := append(make([]*Node, 0), items[0]...)
+			for i, s := range seps {
+				nodes = append(append(nodes, s...), items[i+1]...)
+			}
+			return filter(negate(IsPhantom), nodes), r
+		}
+	}
+}
+
+func SepEndBy(c, sep Combinator) Combinator {
+	return AtWhy(SepBy1(c, sep), AtWhy(sep))
+}
+
+func Between(open, close, c Combinator) Combinator {
+	return AtDot(_glob(open), c, _glob(close))
+}
+
+func Count(n int, c Combinator) Combinator {
+	return Repeat(n, n, c)
+}
+
+// min > max (max >= 0) 时没有能匹配的次数, 构造时 panic
+func Repeat(min, max int, c Combinator) Combinator {
+	if max >= 0 && min > max {
+		panic("Repeat: min must not exceed max")
+	}
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			nodes, rest := make([]*Node, 0), toks
+			for n := 0; max < 0 || n < max; n++ {
+				t, r := ApplyCheck(c, rest, stk, ctx)
+				if t == nil {
+					if n < min {
+						return nil, nil
+					}
+					break
+				}
+				progress := len(r) < len(rest)
+				nodes, rest = append(nodes, t...), r
+				if !progress && n+1 >= min {
+					break
+				}
+			}
+			return nodes, rest
+		}
+	}
+}
+
+func ManyTill(c, end Combinator) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			nodes, rest := make([]*Node, 0), toks
+			for {
+				if t, r := ApplyCheck(end, rest, stk, ctx); t != nil {
+					return nodes, r
+				}
+				t, r := ApplyCheck(c, rest, stk, ctx)
+				if t == nil || len(r) == len(rest) {
+					return nil, nil
+				}
+				nodes, rest = append(nodes, t...), r
+			}
+		}
+	}
+}
+
+func Option(c Combinator, def ...*Node) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			if t, r := ApplyCheck(c, toks, stk, ctx); t != nil {
+				return t, r
+			}
+			return append(make([]*Node, 0, len(def)), def...), toks
+		}
+	}
+}
+
+func LookAhead(c Combinator) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			if t, _ := lookahead(c(), toks, Ext(c, toks, stk), ctx); t == nil {
+				return nil, nil
+			}
+			return make([]*Node, 0), toks
+		}
+	}
+}
+
+func NotFollowedBy(c Combinator) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			if t, _ := lookahead(c(), toks, Ext(c, toks, stk), ctx); t != nil {
+				return nil, nil
+			}
+			return make([]*Node, 0), toks
+		}
+	}
+}
+
+// 匹配 c (op c)*, 返回各个 c 和 op 的结果
+func chain(c, op Combinator, toks []*Node, stk []*Pair, ctx interface{}) (items, ops [][]*Node, rest []*Node) {
+	t, r := ApplyCheck(c, toks, stk, ctx)
+	if t == nil {
+		return nil, nil, nil
+	}
+	items = append(items, t)
+	for len(r) > 0 {
+		s := mark(ctx)
+		o, r1 := ApplyCheck(op, r, stk, ctx)
+		if o == nil {
+			break
+		}
+		t, r2 := ApplyCheck(c, r1, stk, ctx)
+		if t == nil || len(r2) == len(r) {
+			// 丢弃 op, 撤销其中对状态的修改
+			reset(ctx, s)
+			break
+		}
+		items, ops, r = append(items, t), append(ops, o), r2
+	}
+	return items, ops, r
+}
+
+func ChainL1(c, op Combinator, f func(left, op, right []*Node) []*Node) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			items, ops, r := chain(c, op, toks, stk, ctx)
+			if items == nil {
+				return nil, nil
+			}
+			acc := items[0]
+			for i, o := range ops {
+				acc = f(acc, o, items[i+1])
+			}
+			return acc, r
+		}
+	}
+}
+
+func ChainR1(c, op Combinator, f func(left, op, right []*Node) []*Node) Combinator {
+	return func() Parser {
+		return func(toks []*Node, stk []*Pair, ctx interface{}) ([]*Node, []*Node) {
+			items, ops, r := chain(c, op, toks, stk, ctx)
+			if items == nil {
+				return nil, nil
+			}
+			acc := items[len(items)-1]
+			for i := len(ops) - 1; i >= 0; i-- {
+				acc = f(items[i], ops[i], acc)
+			}
+			return acc, r
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package parser
+
+import "testing"
+
+func TestStdCombinators(t *testing.T) {
+	SetParameters()
+	id := P["$pred"](Preds["id"])
+	comma := S["@_"](",")
+	for _, c := range []struct {
+		name string
+		c    Combinator
+		src  string
+		want string
+		rest int
+	}{
+		{"SepBy", SepBy(id, comma), "a , b , c", "token:a token:b token:c", 0},
+		{"SepBy 空", SepBy(id, comma), "1", "", 1},
+		{"SepBy 末尾的 sep", SepBy(id, comma), "a , b ,", "token:a token:b", 1},
+		{"SepBy1", SepBy1(id, comma), "1", "<nil>", 0},
+		{"SepEndBy", SepEndBy(id, comma), "a , b ,", "token:a token:b", 0},
+		{"SepEndBy 保留 sep", SepEndBy(id, S["$$"](",")), "a , b ,", "token:a token:, token:b token:,", 0},
+		// c 和 sep 都可以为空, 不会死循环
+		{"SepBy 空循环", SepBy(B["@?"](S["$$"]("x")), B["@?"](comma)), "x x , y", "token:x token:x", 1},
+		{"Between", Between(S["$$"]("("), S["$$"](")"), id), "( a )", "token:a", 0},
+		{"Count", Count(2, id), "a b c", "token:a token:b", 1},
+		{"Count 不够", Count(2, id), "a 1", "<nil>", 0},
+		{"Repeat", Repeat(1, 2, id), "a b c", "token:a token:b", 1},
+		{"Repeat 不限", Repeat(0, -1, id), "a b c", "token:a token:b token:c", 0},
+		{"Repeat 空循环", Repeat(0, -1, B["@?"](id)), "1", "", 1},
+		{"ManyTill", ManyTill(P["$pred"](Preds["token"]), S["@_"](";")), "a b ; c", "token:a token:b", 1},
+		{"ManyTill 没有 end", ManyTill(P["$pred"](Preds["token"]), S["@_"](";")), "a b", "<nil>", 0},
+		{"Option", Option(id, &Node{Type: "default"}), "1", "default:", 1},
+		{"LookAhead", B["@seq"](LookAhead(S["$$"]("a")), id), "a", "token:a", 0},
+		{"NotFollowedBy", B["@seq"](NotFollowedBy(S["$$"]("a")), id), "a", "<nil>", 0},
+	} {
+		got, rest := Eval(c.c, Scan(c.src))
+		if dumps(got) != c.want || len(rest) != c.rest {
+			t.Errorf("%s: got %s (%d left), want %s (%d left)", c.name, dumps(got), len(rest), c.want, c.rest)
+		}
+	}
+}
+
+func TestRepeatMinMax(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Repeat(3, 1, c) should panic")
+		}
+	}()
+	Repeat(3, 1, P["$pred"](Preds["id"]))
+}
+
+func TestChain(t *testing.T) {
+	SetParameters()
+	num := P["$pred"](IsNumber)
+	build := func(l, op, r []*Node) []*Node {
+		return []*Node{{Type: op[0].Text, Elts: append(append([]*Node{}, l...), r...)}}
+	}
+	if got, _ := Eval(ChainL1(num, S["$$"]("-"), build), Scan("1 - 2 - 3")); dumps(got) != "(- (- number:1 number:2) number:3)" {
+		t.Errorf("ChainL1: got %s", dumps(got))
+	}
+	if got, _ := Eval(ChainR1(num, S["$$"]("^"), build), Scan("1 ^ 2 ^ 3")); dumps(got) != "(^ number:1 (^ number:2 number:3))" {
+		t.Errorf("ChainR1: got %s", dumps(got))
+	}
+	// 末尾的 op 不属于结果
+	if got, rest := Eval(ChainL1(num, S["$$"]("-"), build), Scan("1 - 2 -")); dumps(got) != "(- number:1 number:2)" || len(rest) != 1 {
+		t.Errorf("ChainL1: got %s (%d left)", dumps(got), len(rest))
+	}
+}
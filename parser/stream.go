@@ -0,0 +1,276 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+)
+
+// 流式扫描
+// --------------------------------------------
+//
+// Lexer 从 io.Reader 按需读取输入, 每次产生一个 token, 只在内存中保留尚未扫描的一小段输入.
+// TokenStream 在其上缓冲 token, 支持 Mark/Reset 回溯, 不再被标记引用的 token 会被丢弃.
+//
+// 组合子仍然作用在 []*Node 上. EvalStream 反复用 c 解析流的开头: 先取一个窗口的 token,
+// 末尾放一个占位 token, 解析时读到占位 token (窗口不够) 就加倍窗口重新解析,
+// 否则结果与读入全部输入时相同, 取走 c 消耗的 token 后继续. 因此内存占用只与单次解析
+// 需要看到的 token 数有关, 不要求输入按括号划分, 如 calc 的 1 + 2 仍然整体解析.
+// 判断是否读到占位 token 依赖 token 上的谓词 ($$, $pred, @! 等), 字符级的组合子不能用于流.
+
+const lexerChunk = 4096
+
+type Lexer struct {
+	r    io.Reader
+	buf  string // 尚未扫描的输入
+	base int    // buf[0] 在整个输入中的偏移
+	eof  bool
+
+	lay   *layout
+	queue []*Node // 缩进模式下已产生但尚未返回的 token
+}
+
+func NewLexer(r io.Reader) *Lexer {
+	return &Lexer{r: r, lay: newLayout()}
+}
+
+// 读入更多输入. 每次至少读入与 buf 等长的输入, 跨越多次读取的长 token 总的重新扫描量与其长度成正比
+func (l *Lexer) fill() error {
+	want := len(l.buf)
+	if want < lexerChunk {
+		want = lexerChunk
+	}
+	p := make([]byte, want)
+	read := 0
+	defer func() { l.buf += string(p[:read]) }()
+	for read == 0 || read < len(l.buf) {
+		n, err := l.r.Read(p[read:])
+		read += n
+		if err == io.EOF {
+			l.eof = true
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *Lexer) scan() (tok *Node, end int, err *ScanError) {
+	defer func() {
+		if x := recover(); x != nil {
+			e, ok := x.(*ScanError)
+			if !ok {
+				panic(x)
+			}
+			tok, end, err = nil, 0, e
+		}
+	}()
+	tok, end = scanToken(l.buf, 0)
+	return tok, end, nil
+}
+
+// 下一个 token, 输入结束时返回 eof 节点
+func (l *Lexer) Next() (*Node, error) {
+	for {
+		if len(l.queue) > 0 {
+			tok := l.queue[0]
+			l.queue = l.queue[1:]
+			return tok, nil
+		}
+		tok, end, err := l.scan()
+		// 未读完时, token 可能延续到后面的输入中
+		if !l.eof && (err != nil || end >= len(l.buf)) {
+			if err := l.fill(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if err != nil {
+			err.Pos += l.base
+			return nil, err
+		}
+		nodes := []*Node{tok}
+		if indent_mode {
+			if nodes, err = l.layout(tok); err != nil {
+				return nil, err
+			}
+		}
+		for _, n := range nodes {
+			n.Start += l.base
+			n.End += l.base
+		}
+		l.buf, l.base = l.buf[end:], l.base+end
+		l.queue = nodes
+	}
+}
+
+// tok 及其之前的 newline, indent, dedent
+func (l *Lexer) layout(tok *Node) (nodes []*Node, err *ScanError) {
+	defer func() {
+		if x := recover(); x != nil {
+			e, ok := x.(*ScanError)
+			if !ok {
+				panic(x)
+			}
+			e.Pos += l.base
+			nodes, err = nil, e
+		}
+	}()
+	return append(l.lay.before(l.buf, 0, tok), tok), nil
+}
+
+type TokenStream struct {
+	lex   *Lexer
+	toks  []*Node // 缓冲的 token, toks[0] 的序号为 off
+	off   int
+	pos   int
+	marks []int
+	err   error
+}
+
+func NewTokenStream(r io.Reader) *TokenStream {
+	return &TokenStream{lex: NewLexer(r)}
+}
+
+// 当前 token, 输入结束或出错时返回 nil
+func (ts *TokenStream) Peek() *Node {
+	for ts.pos-ts.off >= len(ts.toks) {
+		if ts.err != nil {
+			return nil
+		}
+		tok, err := ts.lex.Next()
+		if err != nil {
+			ts.err = err
+			return nil
+		}
+		if tok.Type == EofType {
+			return nil
+		}
+		ts.toks = append(ts.toks, tok)
+	}
+	return ts.toks[ts.pos-ts.off]
+}
+
+func (ts *TokenStream) Next() *Node {
+	tok := ts.Peek()
+	if tok != nil {
+		ts.pos++
+		ts.discard()
+	}
+	return tok
+}
+
+// 标记当前位置, 之后可以 Reset 回到这里, 用完后需要 Release
+func (ts *TokenStream) Mark() int {
+	ts.marks = append(ts.marks, ts.pos)
+	return ts.pos
+}
+
+func (ts *TokenStream) Reset(mark int) {
+	if mark < ts.off {
+		panic("token stream: reset to a released mark")
+	}
+	ts.pos = mark
+}
+
+func (ts *TokenStream) Release(mark int) {
+	for i, m := range ts.marks {
+		if m == mark {
+			ts.marks = append(ts.marks[:i], ts.marks[i+1:]...)
+			break
+		}
+	}
+	ts.discard()
+}
+
+// 丢弃当前位置和所有标记之前的 token
+func (ts *TokenStream) discard() {
+	low := ts.pos
+	for _, m := range ts.marks {
+		if m < low {
+			low = m
+		}
+	}
+	n := low - ts.off
+	for i := 0; i < n; i++ {
+		ts.toks[i] = nil
+	}
+	ts.toks, ts.off = ts.toks[n:], low
+}
+
+// 扫描或读取中遇到的错误
+func (ts *TokenStream) Err() error {
+	return ts.err
+}
+
+// 从 mark 开始的至多 n 个 token 的副本, 流在其中结束时 done 为 true
+func (ts *TokenStream) window(mark, n int) (toks []*Node, done bool) {
+	ts.Reset(mark)
+	for i := 0; i < n; i++ {
+		if ts.Peek() == nil {
+			done = true
+			break
+		}
+		ts.pos++
+	}
+	toks = append(make([]*Node, 0, ts.pos-mark+1), ts.toks[mark-ts.off:ts.pos-ts.off]...)
+	ts.Reset(mark)
+	return toks, done
+}
+
+// 窗口末尾占位 token 的类型, 代表窗口之外尚未读取的输入
+const moreType = "more"
+
+// 解析器查看 toks[0] 时调用, 读到占位 token 说明窗口不够
+func reachMore(toks []*Node, ctx interface{}) {
+	if len(toks) > 0 && toks[0].Type == moreType {
+		if st, ok := ctx.(*ParseState); ok {
+			st.more = true
+		}
+	}
+}
+
+const streamWindow = 64
+
+// 反复用 c 解析 ts 剩余的部分, 每次的结果交给 f, f 返回 false 或输入结束时停止.
+// c 失败或没有消耗 token 时返回 ParseError.
+func EvalStream(c Combinator, ts *TokenStream, f func([]*Node) bool) error {
+	for ts.Peek() != nil {
+		mark := ts.Mark()
+		var t, r []*Node
+		var err error
+		for n := streamWindow; ; n *= 2 {
+			toks, done := ts.window(mark, n)
+			if !done {
+				end := toks[len(toks)-1].End
+				toks = append(toks, &Node{Type: moreType, Start: end, End: end})
+			}
+			st := NewParseState(nil)
+			t, r, err = EvalState(c, toks, st)
+			if done || !st.more {
+				if t != nil {
+					ts.pos = mark + len(toks) - len(r)
+				}
+				break
+			}
+		}
+		first := ts.toks[mark-ts.off]
+		ts.Release(mark)
+		if ts.Err() != nil && (err != nil || t == nil) {
+			return ts.Err()
+		}
+		if err != nil {
+			return err
+		}
+		if t == nil {
+			return &ParseError{Pos: first.Start, Msg: fmt.Sprintf("cannot parse %q", first.Text)}
+		}
+		if ts.pos == mark {
+			return &ParseError{Pos: first.Start, Msg: fmt.Sprintf("no progress at %q", first.Text)}
+		}
+		if !f(t) {
+			return nil
+		}
+	}
+	return ts.Err()
+}
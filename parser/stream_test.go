@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func streamDump(t *testing.T, s string) string {
+	lex := NewLexer(iotest.OneByteReader(strings.NewReader(s)))
+	ret := ""
+	for {
+		tok, err := lex.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Type == EofType {
+			return ret
+		}
+		ret += tok.Type + ":" + tok.Text + "@" + strconv.Itoa(tok.Start) + "-" + strconv.Itoa(tok.End) + " "
+	}
+}
+
+// 逐字节读取时与 Scan 的结果相同, 包括跨越多次读取的长 token
+func TestLexer(t *testing.T) {
+	SetParameters()
+	long := strings.Repeat("x", 3*lexerChunk)
+	for _, src := range []string{
+		`(SELECT (f "a b" 1.5) x) // c`,
+		`(a "` + long + `" ` + long + `)`,
+		"名字 #\\λ",
+	} {
+		if got, want := streamDump(t, src), scanDump(src); got != want {
+			t.Errorf("%.40q: got %.200s, want %.200s", src, got, want)
+		}
+	}
+	lex := NewLexer(strings.NewReader(`a "b`))
+	lex.Next()
+	if _, err := lex.Next(); err == nil || err.Error() != "2: unterminated string" {
+		t.Errorf("error = %v", err)
+	}
+	boom := errors.New("boom")
+	if _, err := NewLexer(iotest.ErrReader(boom)).Next(); err != boom {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestTokenStreamMark(t *testing.T) {
+	SetParameters()
+	ts := NewTokenStream(strings.NewReader("a b c d"))
+	ts.Next()
+	m := ts.Mark()
+	if ts.Next().Text != "b" || ts.Next().Text != "c" {
+		t.Fatal("Next")
+	}
+	ts.Reset(m)
+	if ts.Peek().Text != "b" {
+		t.Errorf("Reset: got %s", ts.Peek().Text)
+	}
+	ts.Next()
+	ts.Release(m)
+	// 释放标记后之前的 token 被丢弃
+	if ts.off != 2 || len(ts.toks) != 0 && ts.toks[0].Text != "c" {
+		t.Errorf("off = %d, toks = %s", ts.off, dumps(ts.toks))
+	}
+	if ts.Next().Text != "c" || ts.Next().Text != "d" || ts.Next() != nil || ts.Err() != nil {
+		t.Error("end of stream")
+	}
+}
+
+func evalStream(c Combinator, src string) ([]string, error) {
+	ret := make([]string, 0)
+	err := EvalStream(c, NewTokenStream(strings.NewReader(src)), func(t []*Node) bool {
+		ret = append(ret, dumps(t))
+		return true
+	})
+	return ret, err
+}
+
+func TestEvalStream(t *testing.T) {
+	initSexp()
+	got, err := evalStream(Parens, "(a (b)) [c] (d")
+	if want := "(sexp token:a (sexp token:b))|(sexp token:c)"; strings.Join(got, "|") != want || err == nil || err.Error() != `12: cannot parse "("` {
+		t.Errorf("got %s %v", strings.Join(got, "|"), err)
+	}
+
+	// 不按括号划分的文法, 一次解析可以跨越多个窗口
+	num := P["$pred"](Preds["number"])
+	sum := AtInfixLeft("add", num, S["$$"]("+"))
+	src := "1" + strings.Repeat(" + 1", 3*streamWindow) + " 2 + 3 4"
+	got, err = evalStream(B["@or"](sum, num), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || strings.Count(got[0], "number:1") != 3*streamWindow+1 || got[1] != "(add number:2 token:+ number:3)" || got[2] != "number:4" {
+		t.Errorf("got %d results: %.80s", len(got), strings.Join(got, "|"))
+	}
+}
+
+func TestEvalStreamErrors(t *testing.T) {
+	SetParameters()
+	// 剪枝的错误在窗口加倍后仍然存在才报告
+	c := B["@seq"](S["@_"]("("), B["@^"](B["@*"](P["$pred"](Preds["id"])), S["@_"](")")))
+	src := "(" + strings.Repeat(" a", 2*streamWindow) + ") (a 1)"
+	got, err := evalStream(c, src)
+	if len(got) != 1 || err == nil || !strings.HasSuffix(err.Error(), `unexpected "1"`) {
+		t.Errorf("got %d results, error %v", len(got), err)
+	}
+	if _, err := evalStream(c, `(a "b`); err == nil || err.Error() != "3: unterminated string" {
+		t.Errorf("error = %v", err)
+	}
+	if _, err := evalStream(C["$none"], `a`); err == nil || err.Error() != `0: no progress at "a"` {
+		t.Errorf("error = %v", err)
+	}
+	// f 返回 false 时停止
+	n := 0
+	EvalStream(P["$pred"](Preds["id"]), NewTokenStream(strings.NewReader("a b c")), func([]*Node) bool { n++; return n < 2 })
+	if n != 2 {
+		t.Errorf("f called %d times", n)
+	}
+}
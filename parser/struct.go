@@ -1,5 +1,10 @@
 package parser
 
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
 const (
 	CommentType   = "comment"
 	PhantomType   = "phantom"
@@ -8,13 +13,21 @@ const (
 	CharacterType = "character"
 	NewlineType   = "newline"
 	EofType       = "eof"
+	ErrorType     = "error"
+	NumberType    = "number"
+	IndentType    = "indent"
+	DedentType    = "dedent"
+	RuneType      = "rune"
+	ValueType     = "value"
 )
 
 type Node struct {
-	Type string
-	Elts []*Node
-	Text string
-	Ctx  interface{}
+	Type  string
+	Elts  []*Node
+	Text  string
+	Ctx   interface{}
+	Value interface{} // 字面量的值, 如字符串解码转义后的内容
+	Op    string      // 运算符节点的运算符, 见 BinaryBuilder
 
 	Start, End, Size int
 }
@@ -55,68 +68,141 @@ func IsNewline(n *Node) bool {
 	return NewlineType == n.Type
 }
 
+func IsNumber(n *Node) bool {
+	return NumberType == n.Type
+}
+
+func IsIndent(n *Node) bool {
+	return IndentType == n.Type
+}
+
+func IsDedent(n *Node) bool {
+	return DedentType == n.Type
+}
+
+func IsError(n *Node) bool {
+	return ErrorType == n.Type
+}
+
 var (
 	left_recur_detection    = false
 	delims                  = []string{"(", ")", "[", "]", "{", "}", "'", "`", ","}
 	line_comment            = []string{";"}
-	comment_start           = "#|"
-	comment_end             = "|#"
+	block_comments          = [][2]string{{"#|", "|#"}}
+	nested_comments         = false
 	operators               = []string{}
-	quotation_marks         = []string{"\"", "'"}
+	quotation_marks         = []string{"\""} // ' 默认是分隔符
+	raw_quotation_marks     = []string{}
+	doubled_quote_escape    = false
 	lisp_char               = []string{"#\\", "?\\"}
 	significant_whitespaces = []string{}
+
+	delims_trie    = newTrie(delims)
+	operators_trie = newTrie(operators)
 )
 
 func SetDelims(x ...string) {
 	delims = x
+	delims_trie = newTrie(x)
 }
 
 func SetLineComment(x ...string) {
 	line_comment = x
 }
 
+// 只有一种块注释时使用, 设置第一对块注释的开始和结束
 func SetCommentStart(x string) {
-	comment_start = x
+	if len(block_comments) == 0 {
+		block_comments = [][2]string{{"", ""}}
+	}
+	block_comments[0][0] = x
 }
 
 func SetCommentEnd(x string) {
-	comment_end = x
+	if len(block_comments) == 0 {
+		block_comments = [][2]string{{"", ""}}
+	}
+	block_comments[0][1] = x
+}
+
+// 多种块注释, 如 SetBlockComments([2]string{"/*", "*/"}, [2]string{"{-", "-}"})
+// x 会被复制, SetCommentStart/End 不会修改调用者的切片
+func SetBlockComments(x ...[2]string) {
+	block_comments = append([][2]string(nil), x...)
+}
+
+// 块注释是否可以嵌套, 嵌套时 /* a /* b */ c */ 是一个注释
+func SetNestedComments(x bool) {
+	nested_comments = x
 }
 
 func SetOperators(x ...string) {
 	operators = x
+	operators_trie = newTrie(x)
 }
 
 func SetQuotationMarks(x ...string) {
 	quotation_marks = x
 }
 
+// 原样字符串的引号, 如 "`", 其中不处理转义. 引号先于分隔符判断, 因此也可以是分隔符
+func SetRawQuotationMarks(x ...string) {
+	raw_quotation_marks = x
+}
+
+// SQL 风格, 字符串中连续两个引号表示一个引号
+func SetDoubledQuoteEscape(x bool) {
+	doubled_quote_escape = x
+}
+
 func SetLispChar(x ...string) {
 	lisp_char = x
 }
 
+// 扫描为 newline 的空白. 缩进模式下换行由缩进规则产生, 这里的设置不起作用
 func SetSignificantWhitespaces(x ...string) {
 	significant_whitespaces = x
 }
 
+// 保存当前的扫描参数, 调用返回的函数恢复
+func saveScanParameters() func() {
+	ds, lc, bc, nc := delims, line_comment, block_comments, nested_comments
+	ops, qs, rqs, dq := operators, quotation_marks, raw_quotation_marks, doubled_quote_escape
+	lisp, ws, rules := lisp_char, significant_whitespaces, token_rules
+	indent, tab, start, part := indent_mode, tab_width, id_start, id_part
+	return func() {
+		SetDelims(ds...)
+		line_comment, block_comments, nested_comments = lc, bc, nc
+		SetOperators(ops...)
+		quotation_marks, raw_quotation_marks, doubled_quote_escape = qs, rqs, dq
+		lisp_char, significant_whitespaces, token_rules = lisp, ws, rules
+		indent_mode, tab_width, id_start, id_part = indent, tab, start, part
+	}
+}
+
+func significantAt(s string, start int) bool {
+	return !indent_mode && StartWithOneOf(s, start, significant_whitespaces) != ""
+}
+
+// 以下判断字符的函数接受单个字符 (一个 rune 的 UTF-8 编码)
+func singleRune(s string) (rune, bool) {
+	r, size := utf8.DecodeRuneInString(s)
+	return r, len(s) > 0 && size == len(s) && r != utf8.RuneError
+}
+
 func IsWhitespace(s string) bool {
-	return s == "\t" || s == "\n" || s == "\v" || s == "\f" || s == "\r" || s == " "
+	r, ok := singleRune(s)
+	return ok && unicode.IsSpace(r)
 }
 
 func IsAlpha(s string) bool {
-	return s == "a" || s == "b" || s == "c" || s == "d" || s == "e" || s == "f" || s == "g" ||
-		s == "h" || s == "i" || s == "j" || s == "k" || s == "l" || s == "m" || s == "n" ||
-		s == "o" || s == "p" || s == "q" || s == "r" || s == "s" || s == "t" || s == "u" ||
-		s == "v" || s == "w" || s == "x" || s == "y" || s == "z" || s == "A" || s == "B" ||
-		s == "C" || s == "D" || s == "E" || s == "F" || s == "G" || s == "H" || s == "I" ||
-		s == "J" || s == "K" || s == "L" || s == "M" || s == "N" || s == "O" || s == "P" ||
-		s == "Q" || s == "R" || s == "S" || s == "T" || s == "U" || s == "V" || s == "W" ||
-		s == "X" || s == "Y" || s == "Z"
+	r, ok := singleRune(s)
+	return ok && unicode.IsLetter(r)
 }
 
 func IsDigit(s string) bool {
-	return s == "0" || s == "1" || s == "2" || s == "3" || s == "4" || s == "5" || s == "6" ||
-		s == "7" || s == "8" || s == "9"
+	r, ok := singleRune(s)
+	return ok && unicode.IsDigit(r)
 }
 
 func IsDelim(c string) bool {
@@ -128,26 +214,45 @@ func IsDelim(c string) bool {
 	return false
 }
 
+// 标识符的首字符和后续字符, 默认同 Go: 字母或 `_` 开头, 后接字母, 数字, `_` 和组合符号
+var (
+	id_start = func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r)
+	}
+	id_part = func(r rune) bool {
+		return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.In(r, unicode.Mn, unicode.Mc, unicode.Pc)
+	}
+)
+
+func SetIdStart(f func(rune) bool) {
+	id_start = f
+}
+
+func SetIdPart(f func(rune) bool) {
+	id_part = f
+}
+
+func IsIdStart(r rune) bool {
+	return id_start(r)
+}
+
+func IsIdPart(r rune) bool {
+	return id_part(r)
+}
+
 func IsId(s string) bool {
 	if len(s) == 0 {
 		return false
 	}
-	if IsAlpha(s[0:1]) || "_" == s[0:1] {
-		var loop func(i int) bool
-		loop = func(i int) bool {
-			if i >= len(s) {
-				return true
-			} else {
-				c := s[i : i+1]
-				if IsAlpha(c) || IsDigit(c) || "_" == c {
-					return loop(i + 1)
-				}
-				return false
-			}
+	for i, r := range s {
+		if r == utf8.RuneError {
+			return false
+		}
+		if i == 0 && !id_start(r) || i > 0 && !id_part(r) {
+			return false
 		}
-		return loop(1)
 	}
-	return false
+	return true
 }
 
 func IsNumeral(s string) bool {
@@ -171,30 +276,31 @@ func StartWith(s string, start int, prefix string) string {
 	return ""
 }
 
+// prefixes 中 s[start:] 的最长前缀
 func StartWithOneOf(s string, start int, prefixes []string) string {
-	if len(prefixes) == 0 {
-		return ""
-	}
-	if prefix := prefixes[0]; StartWith(s, start, prefix) != "" {
-		return prefix
+	longest := ""
+	for _, prefix := range prefixes {
+		if len(prefix) > len(longest) && StartWith(s, start, prefix) != "" {
+			longest = prefix
+		}
 	}
-	return StartWithOneOf(s, start, prefixes[1:])
+	return longest
 }
 
 func FindNext(s string, start int, pred func(string, int) bool) int {
-	if len(s) <= start {
-		return -1
-	}
-	if pred(s, start) {
-		return start
+	for ; start < len(s); start++ {
+		if pred(s, start) {
+			return start
+		}
 	}
-	return FindNext(s, 1+start, pred)
+	return -1
 }
 
+// 最长匹配, 如 `>>=` 优先于 `>>` 和 `>`, 与 SetDelims 中的顺序无关
 func FindDelim(s string, start int) string {
-	return StartWithOneOf(s, start, delims)
+	return delims_trie.longest(s, start)
 }
 
 func FindOperator(s string, start int) string {
-	return StartWithOneOf(s, start, operators)
+	return operators_trie.longest(s, start)
 }
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"regexp"
+)
+
+// 自定义 token
+// --------------------------------------------
+//
+// 除了固定的分隔符, 操作符, 引号等, 可以用一组 TokenRule 声明带类型的 token,
+// 比如标识符, 日期字面量. 每条规则给出一个正则或匹配函数, 以及优先级.
+// 扫描时在跳过空白和注释之后, 先尝试所有规则: 取匹配最长的一条, 长度相同时取优先级高的,
+// 再相同时取先声明的; 生成的节点 Type 为规则名. 没有规则匹配时按原来的方式扫描.
+// 单条正则内部也取最长匹配 (POSIX 语义), 如 `<|<=` 匹配 <= 而不是 <.
+// 文法中用 ($kind name) 匹配某一类 token.
+//
+//	SetTokenRules(
+//		NewTokenRule("date", `\d{4}-\d{2}-\d{2}`, 1),
+//		NewTokenRule("id", `[A-Za-z_]\w*`, 0),
+//		NewTokenRule(TokenType, `SELECT|FROM|WHERE`, 1), // 关键字仍是普通 token
+//	)
+
+type TokenRule struct {
+	Name     string
+	Pattern  *regexp.Regexp                // 从 start 处开始匹配
+	Match    func(s string, start int) int // 返回匹配的长度, 不匹配时返回 0
+	Priority int
+
+	anchored *regexp.Regexp
+}
+
+func NewTokenRule(name, pattern string, priority int) *TokenRule {
+	return &TokenRule{Name: name, Pattern: regexp.MustCompile(pattern), Priority: priority}
+}
+
+var token_rules []*TokenRule
+
+func SetTokenRules(x ...*TokenRule) {
+	for _, rule := range x {
+		if rule.Pattern != nil {
+			// Go 的正则默认取最左的分支, 不是最长的
+			rule.anchored = regexp.MustCompile(`^(?:` + rule.Pattern.String() + `)`)
+			rule.anchored.Longest()
+		}
+	}
+	token_rules = x
+}
+
+func (rule *TokenRule) match(s string, start int) int {
+	if rule.Match != nil {
+		return rule.Match(s, start)
+	}
+	if rule.anchored == nil {
+		return 0
+	}
+	if loc := rule.anchored.FindStringIndex(s[start:]); loc != nil {
+		return loc[1]
+	}
+	return 0
+}
+
+// 最长匹配的规则, 没有规则匹配时返回 nil
+func scanTokenRule(s string, start int) (*Node, int) {
+	var best *TokenRule
+	size := 0
+	for _, rule := range token_rules {
+		n := rule.match(s, start)
+		if n > size || n > 0 && n == size && rule.Priority > best.Priority {
+			best, size = rule, n
+		}
+	}
+	if best == nil {
+		return nil, start
+	}
+	end := start + size
+	return NewNode(best.Name, start, end, nil, s[start:end], 0, nil), end
+}
+
+// 匹配 Type 为 name 的 token, 字符输入上先扫描出 token
+// $kind
+func Kind(name string) Combinator {
+	return tokenPred(func(x *Node) bool {
+		return x.Type == name
+	})
+}
@@ -0,0 +1,41 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenRules(t *testing.T) {
+	SetParameters()
+	defer SetTokenRules()
+	SetTokenRules(
+		NewTokenRule("date", `\d{4}-\d{2}-\d{2}`, 1),
+		NewTokenRule("cmp", `<|<=|<<=`, 0),
+		NewTokenRule("word", `[a-z]+`, 0),
+		NewTokenRule("kw", `select|from`, 1),
+		NewTokenRule("name", `[a-z]+`, 0),
+		&TokenRule{Name: "hash", Match: func(s string, start int) int {
+			if strings.HasPrefix(s[start:], "#") {
+				return 1 + len(s[start+1:]) - len(strings.TrimLeft(s[start+1:], "0123456789abcdef"))
+			}
+			return 0
+		}},
+	)
+	for _, c := range []struct{ src, want string }{
+		// 单条正则内部取最长的分支
+		{`a<=b`, `word:a@0-1 cmp:<=@1-3 word:b@3-4 `},
+		{`<<= <`, `cmp:<<=@0-3 cmp:<@4-5 `},
+		// 规则之间取最长, 同样长时取优先级高的, 再相同时取先声明的
+		{`selected select`, `word:selected@0-8 kw:select@9-15 `},
+		{`2024-01-02 2024`, `date:2024-01-02@0-10 number:2024@11-15 `},
+		{`#c0ffee (x)`, `hash:#c0ffee@0-7 token:(@8-9 word:x@9-10 token:)@10-11 `},
+	} {
+		if got := scanDump(c.src); got != c.want {
+			t.Errorf("Scan(%q) = %s, want %s", c.src, got, c.want)
+		}
+	}
+	c := B["@seq"](Kind("kw"), Kind("word"))
+	if got, _ := Eval(c, Scan("from t")); dumps(got) != "kw:from word:t" {
+		t.Errorf("got %s", dumps(got))
+	}
+}
@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 解析跟踪
+// --------------------------------------------
+//
+// 设置 Tracer 后, 每次进入/退出具名规则 (:: 包裹的函数, 文法中的规则) 都会回调.
+// pos 为剩余输入第一个 token 的起始偏移, 输入已耗尽时为 -1.
+type Tracer interface {
+	Enter(rule string, pos int)
+	Exit(rule string, pos int, ok bool)
+}
+
+var tracer Tracer
+
+// 传入 nil 关闭跟踪
+func SetTracer(t Tracer) {
+	tracer = t
+}
+
+func position(toks []*Node) int {
+	if len(toks) == 0 {
+		return -1
+	}
+	return toks[0].Start
+}
+
+// 剪枝抛出 ParseError 时也会调用 Exit, 结果为失败
+func traceRule(name string, toks []*Node, parse func() ([]*Node, []*Node)) (t, r []*Node) {
+	tracer.Enter(name, position(toks))
+	defer func() {
+		if t == nil {
+			tracer.Exit(name, position(toks), false)
+		} else {
+			tracer.Exit(name, position(r), true)
+		}
+	}()
+	return parse()
+}
+
+// 按嵌套层次缩进输出
+//
+//	conditionalExpression @0
+//	  logicalOrExpression @0
+//	  logicalOrExpression ok @5
+//	conditionalExpression ok @5
+type PrintTracer struct {
+	W     io.Writer
+	depth int
+}
+
+func (t *PrintTracer) Enter(rule string, pos int) {
+	fmt.Fprintf(t.W, "%s%s @%d\n", strings.Repeat("  ", t.depth), rule, pos)
+	t.depth++
+}
+
+func (t *PrintTracer) Exit(rule string, pos int, ok bool) {
+	t.depth--
+	res := "fail"
+	if ok {
+		res = "ok"
+	}
+	fmt.Fprintf(t.W, "%s%s %s @%d\n", strings.Repeat("  ", t.depth), rule, res, pos)
+}
+
+// 每个事件输出一行 JSON
+//
+//	{"event":"enter","rule":"select","pos":0,"depth":0}
+//	{"event":"exit","rule":"select","pos":12,"depth":0,"ok":true}
+type JSONTracer struct {
+	W     io.Writer
+	depth int
+}
+
+type traceEvent struct {
+	Event string `json:"event"`
+	Rule  string `json:"rule"`
+	Pos   int    `json:"pos"`
+	Depth int    `json:"depth"`
+	Ok    *bool  `json:"ok,omitempty"`
+}
+
+func (t *JSONTracer) emit(e traceEvent) {
+	b, _ := json.Marshal(e)
+	t.W.Write(append(b, '\n'))
+}
+
+func (t *JSONTracer) Enter(rule string, pos int) {
+	t.emit(traceEvent{Event: "enter", Rule: rule, Pos: pos, Depth: t.depth})
+	t.depth++
+}
+
+func (t *JSONTracer) Exit(rule string, pos int, ok bool) {
+	t.depth--
+	t.emit(traceEvent{Event: "exit", Rule: rule, Pos: pos, Depth: t.depth, Ok: &ok})
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintTracer(t *testing.T) {
+	SetParameters()
+	var buf bytes.Buffer
+	SetTracer(&PrintTracer{W: &buf})
+	defer SetTracer(nil)
+
+	var list Combinator
+	atom := Rule("atom", P["$pred"](Preds["id"]))
+	list = Rule("list", B["@seq"](S["@_"]("("), B["@*"](B["@or"](func() Parser { return list() }, atom)), S["@_"](")")))
+	Eval(list, Scan("(a (b))"))
+	want := `list @0
+  list @1
+  list fail @1
+  atom @1
+  atom ok @3
+  list @3
+    list @4
+    list fail @4
+    atom @4
+    atom ok @5
+    list @5
+    list fail @5
+    atom @5
+    atom fail @5
+  list ok @6
+  list @6
+  list fail @6
+  atom @6
+  atom fail @6
+list ok @-1
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestJSONTracer(t *testing.T) {
+	SetParameters()
+	var buf bytes.Buffer
+	SetTracer(&JSONTracer{W: &buf})
+	defer SetTracer(nil)
+
+	Eval(Rule("a", S["$$"]("x")), Scan("y"))
+	want := `{"event":"enter","rule":"a","pos":0,"depth":0}
+{"event":"exit","rule":"a","pos":0,"depth":0,"ok":false}
+`
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+// 剪枝的 ParseError 穿过规则时也要调用 Exit, 之后的缩进不受影响
+func TestTracerCut(t *testing.T) {
+	SetParameters()
+	var buf bytes.Buffer
+	tr := &PrintTracer{W: &buf}
+	SetTracer(tr)
+	defer SetTracer(nil)
+
+	c := Rule("outer", Rule("inner", B["@seq"](S["$$"]("a"), B["@^"](S["$$"]("b")))))
+	if _, _, err := EvalErr(c, Scan("a c")); err == nil {
+		t.Fatal("want a ParseError")
+	}
+	if tr.depth != 0 {
+		t.Errorf("depth = %d after a cut, want 0", tr.depth)
+	}
+	if !strings.Contains(buf.String(), "  inner fail @0\nouter fail @0\n") {
+		t.Errorf("missing exit events:\n%s", buf.String())
+	}
+}
+
+func TestTraceSQLRuleNames(t *testing.T) {
+	var buf bytes.Buffer
+	SetTracer(&PrintTracer{W: &buf})
+	defer SetTracer(nil)
+
+	ParseSQL("(SELECT a b)")
+	for _, name := range []string{"select @0", "  expr @8", "    where @8", "select ok @-1"} {
+		if !strings.Contains(buf.String(), name+"\n") {
+			t.Errorf("trace does not contain %q:\n%s", name, buf.String())
+		}
+	}
+}
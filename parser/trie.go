@@ -0,0 +1,42 @@
+package parser
+
+// 按字节的前缀树, 用于最长匹配分隔符和操作符, 结果与声明顺序无关
+type trie struct {
+	next map[byte]*trie
+	end  bool
+}
+
+func newTrie(words []string) *trie {
+	root := &trie{}
+	for _, w := range words {
+		n := root
+		for i := 0; i < len(w); i++ {
+			if n.next == nil {
+				n.next = make(map[byte]*trie)
+			}
+			child, ok := n.next[w[i]]
+			if !ok {
+				child = &trie{}
+				n.next[w[i]] = child
+			}
+			n = child
+		}
+		// 空串不参与匹配
+		n.end = n != root
+	}
+	return root
+}
+
+// s[start:] 的最长前缀, 没有时返回 ""
+func (t *trie) longest(s string, start int) string {
+	n, end := t, start
+	for i := start; i < len(s); i++ {
+		if n = n.next[s[i]]; n == nil {
+			break
+		}
+		if n.end {
+			end = i + 1
+		}
+	}
+	return s[start:end]
+}
@@ -0,0 +1,40 @@
+package parser
+
+import "testing"
+
+func TestTrieLongest(t *testing.T) {
+	tr := newTrie([]string{"<", "<<=", "<=", "", "->"})
+	for _, c := range []struct {
+		s     string
+		start int
+		want  string
+	}{
+		{"a<<=b", 1, "<<="},
+		{"a<<b", 1, "<"},
+		{"<=", 0, "<="},
+		{"-", 0, ""},
+		{"->x", 0, "->"},
+		{"x", 0, ""},
+		{"<", 1, ""},
+	} {
+		if got := tr.longest(c.s, c.start); got != c.want {
+			t.Errorf("longest(%q, %d) = %q, want %q", c.s, c.start, got, c.want)
+		}
+	}
+}
+
+// 分隔符和操作符按最长匹配, 与声明顺序无关
+func TestScanLongestOperator(t *testing.T) {
+	SetParameters()
+	defer SetOperators()
+	for _, ops := range [][]string{{"=", "==", "!", "!=", "+", "++"}, {"++", "+", "!=", "!", "==", "="}} {
+		SetOperators(ops...)
+		if got, want := scanDump("a==b!=c+++d"), "token:a@0-1 token:==@1-3 token:b@3-4 token:!=@4-6 token:c@6-7 token:++@7-9 token:+@9-10 token:d@10-11 "; got != want {
+			t.Errorf("%v: got %s, want %s", ops, got, want)
+		}
+	}
+	SetDelims("(", "(*", ")")
+	if got, want := scanDump("((*x)"), "token:(@0-1 token:(*@1-3 token:x@3-4 token:)@4-5 "; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,169 @@
+package parser
+
+// 遍历和改写语法树
+// --------------------------------------------
+//
+// 用法同 go/ast:
+//
+//	Inspect(root, func(n *Node) bool {
+//		if n != nil && IsComment(n) {
+//			return false
+//		}
+//		...
+//		return true
+//	})
+//
+// Rewrite 在遍历时可以替换或删除节点, 如去掉所有注释, 把 (paren e) 换成 e:
+//
+//	root = Rewrite(root, func(c *Cursor) bool {
+//		switch n := c.Node(); {
+//		case IsComment(n):
+//			c.Delete()
+//		case n.Type == "paren":
+//			c.Replace(n.Elts[0])
+//		}
+//		return true
+//	}, nil)
+//
+// 替换或删除节点时会为父节点分配新的 Elts, 不会影响共用同一底层数组的其他节点.
+
+type Visitor interface {
+	Visit(n *Node) (w Visitor)
+}
+
+// 先序遍历: v.Visit(n) 返回 w, w 不为 nil 时用 w 遍历 n 的子节点, 最后调用 w.Visit(nil)
+func Walk(v Visitor, n *Node) {
+	if v = v.Visit(n); v == nil {
+		return
+	}
+	for _, e := range n.Elts {
+		if e != nil {
+			Walk(v, e)
+		}
+	}
+	v.Visit(nil)
+}
+
+type inspector func(*Node) bool
+
+func (f inspector) Visit(n *Node) Visitor {
+	if f(n) {
+		return f
+	}
+	return nil
+}
+
+// 先序遍历, f 返回 false 时跳过子节点; 子节点遍历完后调用 f(nil)
+func Inspect(n *Node, f func(*Node) bool) {
+	Walk(inspector(f), n)
+}
+
+// Rewrite 中当前的节点及其位置
+type Cursor struct {
+	path    []*Node // 从根到父节点
+	indices []int   // path 中各节点在其父节点 Elts 中的下标, 根节点为 -1
+	parent  *Node   // 根节点的 parent 是一个临时节点
+	index   int
+	deleted bool
+}
+
+// 当前节点, 已删除时为 nil
+func (c *Cursor) Node() *Node {
+	if c.deleted {
+		return nil
+	}
+	return c.parent.Elts[c.index]
+}
+
+// 父节点, 根节点的为 nil
+func (c *Cursor) Parent() *Node {
+	if len(c.path) == 0 {
+		return nil
+	}
+	return c.path[len(c.path)-1]
+}
+
+// 从根到父节点的各个祖先
+func (c *Cursor) Path() []*Node {
+	return append(make([]*Node, 0, len(c.path)), c.path...)
+}
+
+// 在父节点 Elts 中的下标, 根节点为 -1
+func (c *Cursor) Index() int {
+	if len(c.path) == 0 {
+		return -1
+	}
+	return c.index
+}
+
+// 替换当前节点, 之后遍历的是 n 的子节点; n 为 nil 时同 Delete
+func (c *Cursor) Replace(n *Node) {
+	if c.deleted {
+		panic("Replace: node already deleted")
+	}
+	if n == nil {
+		c.Delete()
+		return
+	}
+	elts := append(make([]*Node, 0, len(c.parent.Elts)), c.parent.Elts...)
+	elts[c.index] = n
+	c.parent.Elts = elts
+}
+
+// 删除当前节点, 不再遍历其子节点, 也不再调用 post
+func (c *Cursor) Delete() {
+	if c.deleted {
+		panic("Delete: node already deleted")
+	}
+	elts := c.parent.Elts
+	c.parent.Elts = append(append(make([]*Node, 0, len(elts)-1), elts[:c.index]...), elts[c.index+1:]...)
+	c.deleted = true
+}
+
+// 遍历 root, 进入节点时调用 pre, 离开时调用 post, 两者都可以为 nil.
+// pre 返回 false 时跳过子节点和 post, post 返回 false 时停止遍历.
+// 返回改写后的根节点, 根节点被删除时为 nil.
+func Rewrite(root *Node, pre, post func(*Cursor) bool) *Node {
+	top := &Node{Elts: []*Node{root}}
+	r := &rewriter{pre: pre, post: post}
+	r.apply(nil, nil, top, 0)
+	if len(top.Elts) == 0 {
+		return nil
+	}
+	return top.Elts[0]
+}
+
+type rewriter struct {
+	pre, post func(*Cursor) bool
+	stop      bool
+}
+
+// 处理 parent.Elts[i], 返回下一个兄弟节点的下标
+func (r *rewriter) apply(path []*Node, indices []int, parent *Node, i int) int {
+	c := &Cursor{path: path, indices: indices, parent: parent, index: i}
+	if c.Node() == nil {
+		return i + 1
+	}
+	if r.pre != nil && !r.pre(c) {
+		return r.next(c)
+	}
+	if c.deleted {
+		return i
+	}
+	n := c.Node()
+	sub, subIndices := append(path[:len(path):len(path)], n), append(indices[:len(indices):len(indices)], c.Index())
+	for j := 0; j < len(n.Elts) && !r.stop; {
+		j = r.apply(sub, subIndices, n, j)
+	}
+	if !r.stop && r.post != nil && !r.post(c) {
+		r.stop = true
+	}
+	return r.next(c)
+}
+
+func (r *rewriter) next(c *Cursor) int {
+	if c.deleted {
+		return c.index
+	}
+	return c.index + 1
+}
@@ -0,0 +1,107 @@
+package parser
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func walkTree() *Node {
+	SetParameters()
+	return ParseSexp("(a (b c) (d // x\n e))")[0]
+}
+
+func TestInspect(t *testing.T) {
+	var order []string
+	Inspect(walkTree(), func(n *Node) bool {
+		if n == nil {
+			order = append(order, "^")
+			return false
+		}
+		if IsComment(n) {
+			return false
+		}
+		order = append(order, n.Type+":"+n.Text)
+		// 跳过 (b c) 的子节点
+		return len(n.Elts) == 0 || n.Elts[0].Text != "b"
+	})
+	if got := strings.Join(order, " "); got != "sexp: token:a ^ sexp: sexp: token:d ^ token:e ^ ^ ^" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	root := walkTree()
+	var paths []string
+	got := Rewrite(root, func(c *Cursor) bool {
+		n := c.Node()
+		switch {
+		case IsComment(n):
+			c.Delete()
+		case n.Text == "c":
+			c.Replace(&Node{Type: "token", Text: "C"})
+		case n.Text == "e":
+			var ps []string
+			for _, p := range c.Path() {
+				ps = append(ps, p.Type)
+			}
+			paths = append(paths, strings.Join(ps, "/"), c.Parent().Elts[0].Text)
+		}
+		return true
+	}, nil)
+	if dump(got) != "(sexp token:a (sexp token:b token:C) (sexp token:d token:e))" {
+		t.Errorf("got %s", dump(got))
+	}
+	if strings.Join(paths, " ") != "sexp/sexp d" {
+		t.Errorf("paths = %v", paths)
+	}
+}
+
+// Replace(nil) 同 Delete; 根节点被删除时返回 nil
+func TestRewriteReplaceNil(t *testing.T) {
+	got := Rewrite(walkTree(), func(c *Cursor) bool {
+		if n := c.Node(); n.Text == "b" || IsComment(n) {
+			c.Replace(nil)
+		}
+		return true
+	}, nil)
+	if dump(got) != "(sexp token:a (sexp token:c) (sexp token:d token:e))" {
+		t.Errorf("got %s", dump(got))
+	}
+	if got := Rewrite(walkTree(), func(c *Cursor) bool { c.Replace(nil); return true }, nil); got != nil {
+		t.Errorf("got %s, want nil", dump(got))
+	}
+}
+
+// 替换和删除都不修改共用的 Elts
+func TestRewriteShared(t *testing.T) {
+	root := walkTree()
+	shared := root.Elts[1].Elts
+	other := &Node{Type: "other", Elts: shared}
+	Rewrite(root.Elts[1], func(c *Cursor) bool {
+		switch c.Node().Text {
+		case "b":
+			c.Replace(&Node{Type: "token", Text: "B"})
+		case "c":
+			c.Delete()
+		}
+		return true
+	}, nil)
+	if dump(root.Elts[1]) != "(sexp token:B)" || dump(other) != "(other token:b token:c)" {
+		t.Errorf("got %s %s", dump(root.Elts[1]), dump(other))
+	}
+}
+
+// post 返回 false 时停止遍历, Index 为在父节点中的下标
+func TestRewriteStop(t *testing.T) {
+	var seen []string
+	Rewrite(walkTree(), nil, func(c *Cursor) bool {
+		if n := c.Node(); IsTokenType(n) {
+			seen = append(seen, n.Text+"@"+strconv.Itoa(c.Index()))
+		}
+		return c.Node().Text != "c"
+	})
+	if got := strings.Join(seen, " "); got != "a@0 b@0 c@1" {
+		t.Errorf("got %s", got)
+	}
+}